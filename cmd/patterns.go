@@ -1,11 +1,22 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/lkendrickd/patterns/internal/pattern"
 	"github.com/lkendrickd/patterns/internal/patterns/adapter"
 	"github.com/lkendrickd/patterns/internal/patterns/singleton"
@@ -26,9 +37,32 @@ import (
 var (
 	// fPattern is the string flag pattern to be used to execute the pattern by name if it exists
 	fPattern = flag.String("pattern", "", "pattern name to execute")
+	// fMetrics is the bool flag to dump the pattern run metrics to stderr when the application exits
+	fMetrics = flag.Bool("metrics", false, "dump the pattern run metrics in Prometheus text format to stderr at exit")
+	// fMetricsAddr is the address the metrics are served on at /metrics, empty disables the server
+	fMetricsAddr = flag.String("metrics-addr", "", "serve the metrics at /metrics on the address such as :9090 until interrupted")
+	// fTrace is the file path the spans of the pattern run are written to, empty disables tracing
+	fTrace = flag.String("trace", "", "file to write the pattern run spans to")
+	// fTraceFormat is the format of the trace file either jsonl or chrome
+	fTraceFormat = flag.String("trace-format", "jsonl", "trace file format: jsonl or chrome (chrome://tracing)")
+	// fCheckLeaks is the goroutine leak detection mode of the pattern run
+	fCheckLeaks pattern.LeakMode
+	// fParams are the key=value parameters handed to the pattern, the flag can be repeated
+	fParams = pattern.Params{}
 )
 
+func init() {
+	flag.Var(&fCheckLeaks, "check-leaks", "detect goroutines leaked by the pattern: off, warn or fail (fail when passed without a value)")
+	flag.Var(fParams, "param", "key=value parameter handed to the pattern, can be repeated")
+}
+
 func main() {
+	os.Exit(run())
+}
+
+// run is the application, it returns the exit code so every exit passes through the deferred
+// cleanup such as closing the trace file which os.Exit would skip
+func run() int {
 	// create a new global slog.Logger - this is done for dependency injection purposes
 	// and to maintain a single logger throughout the application
 	logger := slog.New(
@@ -46,40 +80,111 @@ func main() {
 		*fPattern = value
 	}
 
+	// Create a new PatternOperator with every pattern registered
+	patternOperator := newPatternOperator(logger)
+	patternOperator.LeakCheck = fCheckLeaks
+	patternOperator.Params = fParams
+
+	// Trace the pattern run if a trace file is requested
+	if *fTrace != "" {
+		tracer, err := newTracer(*fTrace, *fTraceFormat)
+		if err != nil {
+			logger.Error(err.Error())
+			return 1
+		}
+		defer tracer.Close()
+		patternOperator.Tracer = tracer
+	}
+
+	// Serve the metrics at /metrics in server mode while the pattern runs and afterwards
+	// until the application is interrupted so they can be scraped
+	var served chan error
+	if *fMetricsAddr != "" {
+		listener, err := net.Listen("tcp", *fMetricsAddr)
+		if err != nil {
+			logger.Error(err.Error())
+			return 1
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		served = make(chan error, 1)
+		go func() {
+			served <- serveMetrics(ctx, listener, patternOperator.Metrics)
+		}()
+		logger.Info("serving metrics", "url", "http://"+listener.Addr().String()+"/metrics")
+	}
+
+	// Run the pattern
+	err := patternOperator.Run(*fPattern)
+
+	// dump the metrics before exiting so failed runs are visible as well
+	if *fMetrics {
+		if _, err := patternOperator.Metrics.WriteTo(os.Stderr); err != nil {
+			logger.Error(err.Error())
+		}
+	}
+
+	// keep serving the metrics of the finished run until interrupted
+	if served != nil {
+		logger.Info("pattern finished, serving metrics until interrupted")
+		if err := <-served; err != nil {
+			logger.Error(err.Error())
+		}
+	}
+
+	if err != nil {
+		// log the error and exit with an exit code of 1 so this can be checked
+		// such as in a ci/cd pipeline or a bash script evocation.
+		logger.Error(err.Error())
+		return 1
+	}
+
+	// Remove the pattern
+	patternOperator.RemovePattern(*fPattern)
+	return 0
+}
+
+// newPatternOperator creates the PatternOperator and registers every pattern of the application
+// the patterns write to the output injected by the operator which defaults to stdout
+func newPatternOperator(logger *slog.Logger) *pattern.PatternOperator {
 	// Create a new PatternOperator
 	patternOperator := pattern.NewPatternOperator([]string{"foo"}, logger)
 	// Add a new pattern to the PatternOperator this adds a default pattern called foo
 	// so the application can be called.
-	patternOperator.AddPattern(pattern.NewPattern(
+	patternOperator.AddPattern(pattern.NewPatternContext(
 		"foo",
-		func() error {
-			fmt.Println("foo")
+		func(ctx context.Context) error {
+			fmt.Fprintln(pattern.Output(ctx), "foo")
 			return nil
 		},
 	))
 
 	// Add the adapter pattern to the PatternOperator
-	patternOperator.AddPattern(pattern.NewPattern(
+	patternOperator.AddPattern(pattern.NewPatternContext(
 		"adapter",
 		adapterExecutor,
 	))
 
+	// Add the adapter pattern over a remote legacy service to the PatternOperator
+	patternOperator.AddPattern(pattern.NewPatternContext(
+		"adapter-http",
+		adapterHTTPExecutor,
+	))
+
+	// Add the observer pattern to the PatternOperator
+	patternOperator.AddPattern(pattern.NewPatternContext(
+		"observer",
+		observerExecutor,
+	))
+
 	// Add the singleton pattern to the PatternOperator
-	patternOperator.AddPattern(pattern.NewPattern(
+	patternOperator.AddPattern(pattern.NewPatternContext(
 		"singleton",
 		singletonExecutor,
 	))
 
-	// Run the pattern
-	if err := patternOperator.Run(*fPattern); err != nil {
-		// log the error and exit with an exit code of 1 so this can be checked
-		// such as in a ci/cd pipeline or a bash script evocation.
-		logger.Error(err.Error())
-		os.Exit(1)
-	}
-
-	// Remove the pattern
-	patternOperator.RemovePattern(*fPattern)
+	return patternOperator
 }
 
 /*##################################################################################
@@ -87,45 +192,290 @@ func main() {
 ##################################################################################*/
 
 // adapterExecutor is the pattern function for the adapter pattern
-func adapterExecutor() error {
+// it opens a child span for each step so the steps show up in the trace
+// the legacy records can be read from a file with the params
+// -param source=csv:records.csv -param field=name (csv, csv-noheader, jsonl or fixed, see adapter.NewFileRecordsAPI)
+// -param mode=structured converts the structured legacy rows into typed customers instead
+// -param dry-run=true prints the diff of the conversion as text or with -param format=json
+// -param report=true prints the migration report, -param audit=file writes the audit log
+// and -param keys=random|content|sequential selects the key strategy
+func adapterExecutor(ctx context.Context) error {
+	params := pattern.ParamsFrom(ctx)
+
+	// Convert the structured rows into typed structs with -param mode=structured
+	if params.Get("mode", "records") == "structured" {
+		return structuredExecutor(ctx)
+	}
+
 	// Create a legacy read only API representing a legacy API
-	legacyAPI := adapter.NewRecordsAPI()
+	var legacyAPI adapter.LegacyAPI = adapter.NewRecordsAPI()
+	if source := params.Get("source", ""); source != "" {
+		fileAPI, err := adapter.ParseSource(source, params.Get("field", ""))
+		if err != nil {
+			return err
+		}
+		legacyAPI = fileAPI
+	}
 	// Create a modern read/write API that represents a modern API
 	// it will convert the records from the legacy API to the modern API
 	// it stores the old Records in a new format called Entries
 	modernAPI := adapter.NewEntriesAPI()
 
+	// The adapter is configured with functional options, -param keys picks the key strategy
+	// a dry run keys the records by content as only those keys are the same in the real run
+	// and let the preview tell changed and unchanged entries apart
+	dryRun := params.Get("dry-run", "false") == "true"
+	keysName := params.Get("keys", "random")
+	if dryRun {
+		keysName = params.Get("keys", "content")
+		if keysName != "content" {
+			return fmt.Errorf("dry-run needs -param keys=content, %s keys differ from the keys of the real run", keysName)
+		}
+	}
+	keys, err := keyStrategy(keysName)
+	if err != nil {
+		return err
+	}
+	opts := []adapter.AdapterOption{adapter.WithKeyStrategy(keys)}
+
+	// Preview the conversion instead of running it with -param dry-run=true
+	if dryRun {
+		return printDryRun(pattern.Output(ctx), adapter.NewAdapter(legacyAPI, modernAPI, opts...), params.Get("format", "text"))
+	}
+
+	// Audit every record in memory for the migration report of -param report=true
+	// and to a JSON lines file with -param audit=audit.jsonl
+	memoryAudit := adapter.NewMemoryAudit()
+	var fileAudit *adapter.JSONLinesAudit
+	if path := params.Get("audit", ""); path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		fileAudit = adapter.NewJSONLinesAudit(file)
+		opts = append(opts, adapter.WithAuditSink(adapter.MultiAudit(memoryAudit, fileAudit)))
+	} else {
+		opts = append(opts, adapter.WithAuditSink(memoryAudit))
+	}
+
 	// Create a new adapter to wrapper both the legacy and modern APIs
-	adapter := adapter.NewAdapter(legacyAPI, modernAPI)
+	recordsAdapter := adapter.NewAdapter(legacyAPI, modernAPI, opts...)
 
 	// Convert the records from the legacy API to the modern API
-	if err := adapter.ConvertRecords(); err != nil {
+	_, span := pattern.StartSpan(ctx, "adapter.convert")
+	err = recordsAdapter.ConvertRecords()
+	span.Finish(err)
+	if err != nil {
 		return err
 	}
 
 	// List the entries from the modern API
-	fmt.Println(adapter.ListEntries())
+	_, span = pattern.StartSpan(ctx, "adapter.list")
+	printEntries(pattern.Output(ctx), recordsAdapter.ListEntries())
+	span.Finish(nil)
+
+	if params.Get("report", "false") == "true" {
+		fmt.Fprint(pattern.Output(ctx), memoryAudit.Report())
+	}
+	if fileAudit != nil {
+		return fileAudit.Err()
+	}
+
+	return nil
+}
+
+// customer is the typed modern record the structured legacy rows are converted into
+type customer struct {
+	ID      int    `adapter:"id"`
+	Name    string `adapter:"name"`
+	Age     int    `adapter:"age"`
+	VIP     bool   `adapter:"vip"`
+	Country string `adapter:"country"`
+	Label   string `adapter:"label"`
+}
+
+// structuredExecutor converts the legacy rows into customers with a declarative schema
+// the columns are renamed and cast, empty values get defaults and the label is computed
+func structuredExecutor(ctx context.Context) error {
+	legacyAPI := adapter.NewRowsAPI()
+
+	schema, err := adapter.NewSchema[customer](legacyAPI.Columns(),
+		adapter.Field{Name: "id", Type: adapter.TypeInt, Required: true},
+		adapter.Field{Name: "name", From: "full_name", Required: true},
+		adapter.Field{Name: "age", Type: adapter.TypeInt},
+		adapter.Field{Name: "vip", Type: adapter.TypeBool, Default: "false"},
+		adapter.Field{Name: "country", Default: "unknown"},
+		adapter.Field{Name: "label", Compute: customerLabel},
+	)
+	if err != nil {
+		return err
+	}
+
+	store := adapter.NewStructStore(func(c customer) string { return fmt.Sprintf("customer-%d", c.ID) })
+
+	_, span := pattern.StartSpan(ctx, "adapter.convert")
+	_, err = adapter.NewStructuredAdapter(legacyAPI.Rows(), schema, store).Convert()
+	span.Finish(err)
+	if err != nil {
+		return err
+	}
+
+	out := pattern.Output(ctx)
+	for _, key := range store.Keys() {
+		c, _ := store.Get(key)
+		fmt.Fprintf(out, "%s: %+v\n", key, c)
+	}
+
+	return nil
+}
+
+// customerLabel computes the label of a customer from its name and age, the age is left
+// out when the legacy row has none as an empty optional field is mapped to nil
+func customerLabel(values map[string]any) (any, error) {
+	if values["age"] == nil {
+		return fmt.Sprint(values["name"]), nil
+	}
+	return fmt.Sprintf("%s (%d)", values["name"], values["age"]), nil
+}
+
+// adapterHTTPExecutor is the pattern function for the adapter pattern over HTTP
+// the legacy records live in a stub legacy service speaking XML over HTTP, they are
+// converted into the modern entries printed as JSON and a change to an entry is
+// written back to the legacy service
+func adapterHTTPExecutor(ctx context.Context) error {
+	out := pattern.Output(ctx)
+
+	// Start the stub legacy service on a local port so the pattern runs offline
+	server := adapter.StartLegacyStub("foo", "bar", "baz")
+	defer server.Close()
+
+	legacyAPI := adapter.NewHTTPRecordsAPI(server.URL, server.Client())
+	modernAPI := adapter.NewEntriesAPI()
+
+	// The content hash keys let the change below find the entry of the bar record
+	bidirectional := adapter.NewBidirectionalAdapter(legacyAPI, modernAPI, adapter.WithKeyStrategy(adapter.ContentHashKeys()))
+
+	_, span := pattern.StartSpan(ctx, "adapter.forward")
+	_, err := bidirectional.Forward()
+	span.Finish(err)
+	if err != nil {
+		return err
+	}
+
+	if err := printEntriesJSON(out, modernAPI.Entries()); err != nil {
+		return err
+	}
+
+	// Change an entry on the modern side and write it back to the legacy service
+	if err := modernAPI.AddEntry(adapter.ContentHashKeys()("bar"), "BAR"); err != nil {
+		return err
+	}
+
+	_, span = pattern.StartSpan(ctx, "adapter.write-back")
+	_, err = bidirectional.WriteBack()
+	span.Finish(err)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "legacy service records after the write back:")
+	for i, record := range legacyAPI.Records() {
+		fmt.Fprintf(out, "%d: %s\n", i, record)
+	}
+
+	return legacyAPI.Err()
+}
+
+// observerExecutor is the pattern function for the observer pattern
+// a subscriber observes the modern API while the adapter converts the legacy records
+// into it and the entries are changed afterwards
+func observerExecutor(ctx context.Context) error {
+	out := pattern.Output(ctx)
+
+	modernAPI := adapter.NewEntriesAPI()
+
+	// Subscribe before any write so every change is observed, the subscriber
+	// is the only one writing to the output until it is closed
+	observed := 0
+	subscription := modernAPI.OnChange(adapter.SubscribeOptions{Buffer: 8, Policy: adapter.BlockWriter},
+		func(event adapter.Event) {
+			observed++
+			fmt.Fprintf(out, "observed %s\n", event)
+		})
+
+	recordsAdapter := adapter.NewAdapter(adapter.NewRecordsAPI(), modernAPI, adapter.WithKeyStrategy(adapter.SequentialKeys()))
+
+	_, span := pattern.StartSpan(ctx, "adapter.convert")
+	err := recordsAdapter.ConvertRecords()
+	span.Finish(err)
+	if err == nil {
+		err = modernAPI.AddEntry("00000002", "BAR")
+	}
+	if err == nil {
+		err = modernAPI.RemoveEntry("00000003")
+	}
+
+	// Close waits for the subscriber to handle the events delivered so far
+	subscription.Close()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "%d events observed\n", observed)
 
 	return nil
 }
 
 // singletonExecutor is the pattern function for the singleton pattern
-func singletonExecutor() error {
-	fmt.Println("creating the singleton calling constructor")
+// a channel is registered and used through the first reference to the singleton and
+// the second reference sees the same channel with its values and counts
+func singletonExecutor(ctx context.Context) error {
+	out := pattern.Output(ctx)
+
+	fmt.Fprintln(out, "creating the singleton calling constructor")
 
 	// Create a new singleton
 	chanOpAlpha := singleton.New()
 
 	// Print the singleton ID
-	fmt.Printf("singleton ID: %s\n", chanOpAlpha.ID)
+	fmt.Fprintf(out, "singleton ID: %s\n", chanOpAlpha.ID)
 
-	fmt.Println("calling the singleton constructor again")
+	// Register a channel with a name of its own as the singleton is shared by every run
+	jobs := "jobs-" + uuid.NewString()
+	if err := chanOpAlpha.Register(jobs, 3); err != nil {
+		return err
+	}
+	defer chanOpAlpha.Unregister(jobs)
+	fmt.Fprintf(out, "registered channel %s with capacity 3\n", jobs)
+
+	for _, job := range []string{"build", "test"} {
+		if err := chanOpAlpha.Send(jobs, job, time.Second); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "sent job %s\n", job)
+	}
+
+	fmt.Fprintln(out, "calling the singleton constructor again")
 
 	// Call the constructor again using a new variable
 	chanOpBravo := singleton.New()
 
 	// Print the singleton ID
-	fmt.Printf("singleton ID: %s\n", chanOpBravo.ID)
+	fmt.Fprintf(out, "singleton ID: %s\n", chanOpBravo.ID)
+
+	// The second reference receives what was sent through the first one
+	job, err := chanOpBravo.Receive(jobs, time.Second)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "received job %s through the second reference\n", job)
+
+	stats, err := chanOpBravo.Stats(jobs)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "channel %s\n", stats)
 
 	return nil
 }
@@ -134,6 +484,135 @@ func singletonExecutor() error {
 # Helper Functions
 ##################################################################################*/
 
+// serveMetrics will serve the metrics at /metrics on the listener until the context is done
+// then shut the server down gracefully
+func serveMetrics(ctx context.Context, listener net.Listener, metrics *pattern.Metrics) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	shutdown := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		timeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- server.Shutdown(timeout)
+	}()
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return <-shutdown
+}
+
+// printEntries is a helper function to print the entries one per line ordered by value
+// the keys are random so ordering by value keeps the output stable between runs
+func printEntries(w io.Writer, entries map[string]string) {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if entries[keys[i]] != entries[keys[j]] {
+			return entries[keys[i]] < entries[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s: %s\n", key, entries[key])
+	}
+}
+
+// printEntriesJSON is a helper function to print the entries as a JSON array ordered by value
+func printEntriesJSON(w io.Writer, entries map[string]string) error {
+	list := make([]adapter.Entry, 0, len(entries))
+	for key, value := range entries {
+		list = append(list, adapter.Entry{Key: key, Value: value})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Value != list[j].Value {
+			return list[i].Value < list[j].Value
+		}
+		return list[i].Key < list[j].Key
+	})
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// keyStrategy is a helper function to return the adapter key strategy of the name
+func keyStrategy(name string) (adapter.KeyStrategy, error) {
+	switch name {
+	case "random":
+		return adapter.RandomKeys(), nil
+	case "content":
+		return adapter.ContentHashKeys(), nil
+	case "sequential":
+		return adapter.SequentialKeys(), nil
+	default:
+		return nil, fmt.Errorf("unknown key strategy %q want random, content or sequential", name)
+	}
+}
+
+// printDryRun is a helper function to print the diff the conversion would make in the format
+func printDryRun(w io.Writer, a *adapter.RecordsAdapter, format string) error {
+	diff, err := a.DryRun()
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "text":
+		_, err = io.WriteString(w, diff.Text())
+	case "json":
+		var data []byte
+		if data, err = diff.JSON(); err == nil {
+			_, err = fmt.Fprintln(w, string(data))
+		}
+	default:
+		err = fmt.Errorf("unknown dry run format %q want text or json", format)
+	}
+	return err
+}
+
+// newTracer is a helper function to create a tracer writing the spans to the file in the given format
+func newTracer(path string, format string) (*pattern.Tracer, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "jsonl":
+		return pattern.NewTracer(&fileExporter{pattern.NewJSONLinesExporter(file), file}), nil
+	case "chrome":
+		return pattern.NewTracer(&fileExporter{pattern.NewChromeTraceExporter(file), file}), nil
+	default:
+		file.Close()
+		return nil, fmt.Errorf("unknown trace format %q", format)
+	}
+}
+
+// fileExporter wraps an exporter and closes the file it writes to once the exporter is closed
+type fileExporter struct {
+	pattern.Exporter
+	file *os.File
+}
+
+// Close will close the exporter and then the file
+func (f *fileExporter) Close() error {
+	if err := f.Exporter.Close(); err != nil {
+		f.file.Close()
+		return err
+	}
+	return f.file.Close()
+}
+
 // getEnv is a helper function to retrieve the environment variable for the pattern if the environment variable exists
 // is not empty then this will override the flag value
 func getEnv(envVar string, fallback string) string {
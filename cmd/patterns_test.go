@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lkendrickd/patterns/internal/pattern"
+	"github.com/lkendrickd/patterns/internal/pattern/patterntest"
+)
+
+var (
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+)
+
+// TestPatternsGolden runs every registered pattern through the operator and compares
+// its output with testdata/<pattern>.golden, run with -update after changing an executor
+func TestPatternsGolden(t *testing.T) {
+	op := newPatternOperator(logger)
+
+	names := make([]string, 0, len(op.Patterns))
+	for name := range op.Patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			op.Output = &buf
+
+			if err := op.RunContext(context.Background(), name); err != nil {
+				t.Fatalf("RunContext(%q) error = %v", name, err)
+			}
+
+			patterntest.Golden(t, name, buf.Bytes(),
+				patterntest.NormalizeUUIDs,
+				patterntest.NormalizeTimestamps,
+			)
+		})
+	}
+}
+
+// TestAdapterParams runs the adapter pattern with params such as the legacy exports of the adapter testdata
+func TestAdapterParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		params pattern.Params
+	}{
+		{"adapter-csv", pattern.Params{"source": "csv:../internal/patterns/adapter/testdata/records.csv", "field": "name"}},
+		{"adapter-jsonl", pattern.Params{"source": "jsonl:../internal/patterns/adapter/testdata/records.jsonl"}},
+		{"adapter-fixed", pattern.Params{"source": "fixed:../internal/patterns/adapter/testdata/records.txt", "field": "4:14"}},
+		{"adapter-dry-run", pattern.Params{"dry-run": "true"}},
+		{"adapter-dry-run-json", pattern.Params{"dry-run": "true", "format": "json"}},
+		{"adapter-structured", pattern.Params{"mode": "structured"}},
+		{"adapter-sequential-keys", pattern.Params{"keys": "sequential"}},
+		{"adapter-report", pattern.Params{"source": "csv:../internal/patterns/adapter/testdata/records.csv", "report": "true"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			op := newPatternOperator(logger)
+			op.Output = &buf
+			op.Params = tt.params
+
+			if err := op.RunContext(context.Background(), "adapter"); err != nil {
+				t.Fatalf("RunContext() error = %v", err)
+			}
+
+			patterntest.Golden(t, tt.name, buf.Bytes(), patterntest.NormalizeUUIDs)
+		})
+	}
+}
+
+// TestAdapterDryRunKeys checks a dry run rejects the key strategies whose keys differ from the real run
+func TestAdapterDryRunKeys(t *testing.T) {
+	tests := []struct {
+		keys    string
+		wantErr bool
+	}{
+		{"content", false},
+		{"random", true},
+		{"sequential", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.keys, func(t *testing.T) {
+			op := newPatternOperator(logger)
+			op.Output = io.Discard
+			op.Params = pattern.Params{"dry-run": "true", "keys": tt.keys}
+
+			if err := op.RunContext(context.Background(), "adapter"); (err != nil) != tt.wantErr {
+				t.Errorf("RunContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestAdapterAuditFile checks the adapter pattern writes one audit line per record with -param audit
+func TestAdapterAuditFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	op := newPatternOperator(logger)
+	op.Output = io.Discard
+	op.Params = pattern.Params{"audit": path}
+
+	if err := op.RunContext(context.Background(), "adapter"); err != nil {
+		t.Fatalf("RunContext() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 3 || !strings.Contains(string(data), `"outcome":"converted"`) {
+		t.Errorf("audit file has %d lines, want 3 converted records:\n%s", lines, data)
+	}
+}
+
+// TestPatternsConformance runs the pattern conformance kit against the adapter, observer and singleton patterns
+func TestPatternsConformance(t *testing.T) {
+	op := newPatternOperator(logger)
+
+	for _, name := range []string{"adapter", "adapter-http", "observer", "singleton"} {
+		t.Run(name, func(t *testing.T) {
+			patterntest.Run(t, op.Patterns[name])
+		})
+	}
+}
+
+func TestPrintEntries(t *testing.T) {
+	var buf bytes.Buffer
+	printEntries(&buf, map[string]string{"b": "2", "a": "3", "c": "1"})
+
+	if got, want := buf.String(), "c: 1\nb: 2\na: 3\n"; got != want {
+		t.Errorf("printEntries() = %q, want %q", got, want)
+	}
+}
+
+// TestExecutorsUseInjectedOutput checks the executors write nothing when the output is discarded
+// and only to the writer carried by the context
+func TestCustomerLabel(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]any
+		want   string
+	}{
+		{"WithAge", map[string]any{"name": "Ada Lovelace", "age": int64(36)}, "Ada Lovelace (36)"},
+		{"EmptyAge", map[string]any{"name": "Ada Lovelace", "age": nil}, "Ada Lovelace"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := customerLabel(tt.values)
+			if err != nil || got != tt.want {
+				t.Errorf("customerLabel() = %v, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecutorsUseInjectedOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		executor func(ctx context.Context) error
+	}{
+		{"adapter", adapterExecutor},
+		{"singleton", singletonExecutor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.executor(pattern.WithOutput(context.Background(), &buf)); err != nil {
+				t.Fatalf("executor error = %v", err)
+			}
+			if buf.Len() == 0 {
+				t.Errorf("executor wrote nothing to the injected output")
+			}
+		})
+	}
+}
+
+// TestServeMetrics checks the metrics of a run are served at /metrics until the context is done
+func TestServeMetrics(t *testing.T) {
+	op := newPatternOperator(logger)
+	op.Output = io.Discard
+	if err := op.Run("foo"); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serveMetrics(ctx, listener, op.Metrics)
+	}()
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), `pattern_runs_total{pattern="foo"} 1`) {
+		t.Errorf("GET /metrics body missing the run\n%s", body)
+	}
+
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("serveMetrics() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serveMetrics() did not return after the context was done")
+	}
+}
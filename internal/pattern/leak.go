@@ -0,0 +1,132 @@
+package pattern
+
+// Leak detection takes a snapshot of every goroutine before a pattern runs and compares
+// it with the goroutines still alive afterwards. Goroutines that were started during the
+// run and did not exit are reported with their stack traces. Concurrency demos are the
+// most likely to leak so the PatternOperator can warn or fail when it happens.
+// NOTE: goroutines started by anything else while the pattern runs are reported as well.
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// LeakTimeout is how long leaked goroutines are given to exit before they are reported
+	LeakTimeout = time.Second
+)
+
+// LeakMode is the mode of the goroutine leak detection of the PatternOperator
+type LeakMode int
+
+const (
+	// LeakCheckOff disables the leak detection
+	LeakCheckOff LeakMode = iota
+	// LeakCheckWarn logs the leaked goroutines as a warning
+	LeakCheckWarn
+	// LeakCheckFail fails the run with a LeakError
+	LeakCheckFail
+)
+
+// String will return the name of the mode
+func (m LeakMode) String() string {
+	switch m {
+	case LeakCheckWarn:
+		return "warn"
+	case LeakCheckFail:
+		return "fail"
+	default:
+		return "off"
+	}
+}
+
+// Set will set the mode by name so the mode can be used as a flag.Value
+// the bool values are accepted as well so -check-leaks on its own means fail
+func (m *LeakMode) Set(value string) error {
+	switch value {
+	case "off", "false":
+		*m = LeakCheckOff
+	case "warn":
+		*m = LeakCheckWarn
+	case "fail", "true":
+		*m = LeakCheckFail
+	default:
+		return fmt.Errorf("unknown leak mode %q want off, warn or fail", value)
+	}
+	return nil
+}
+
+// IsBoolFlag allows the flag to be passed without a value
+func (m *LeakMode) IsBoolFlag() bool {
+	return true
+}
+
+// LeakError is the error returned when a pattern run leaves goroutines behind
+type LeakError struct {
+	Pattern string
+	Stacks  []string // Stacks are the stack traces of the leaked goroutines
+}
+
+// Error will return the error message including the leaked stacks
+func (e *LeakError) Error() string {
+	return fmt.Sprintf("pattern %s leaked %d goroutines:\n\n%s",
+		e.Pattern, len(e.Stacks), strings.Join(e.Stacks, "\n\n"))
+}
+
+// GoroutineSnapshot is the set of goroutines alive at a point in time by goroutine id
+type GoroutineSnapshot map[int64]string
+
+// SnapshotGoroutines will return the stack traces of every running goroutine
+func SnapshotGoroutines() GoroutineSnapshot {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		// the buffer was too small for every stack so grow it and try again
+		buf = make([]byte, 2*len(buf))
+	}
+
+	snapshot := make(GoroutineSnapshot)
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		// every stack starts with a header such as "goroutine 18 [running]:"
+		header := bytes.TrimPrefix(stack, []byte("goroutine "))
+		end := bytes.IndexByte(header, ' ')
+		if end < 0 {
+			continue
+		}
+		id, err := strconv.ParseInt(string(header[:end]), 10, 64)
+		if err != nil {
+			continue
+		}
+		snapshot[id] = string(stack)
+	}
+	return snapshot
+}
+
+// Leaked will return the stacks of the goroutines that are not part of the snapshot
+// waiting up to the timeout for them to exit
+func (s GoroutineSnapshot) Leaked(timeout time.Duration) []string {
+	deadline := time.Now().Add(timeout)
+	for wait := time.Millisecond; ; wait *= 2 {
+		var leaked []string
+		for id, stack := range SnapshotGoroutines() {
+			if _, ok := s[id]; !ok {
+				leaked = append(leaked, stack)
+			}
+		}
+
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			sort.Strings(leaked)
+			return leaked
+		}
+		time.Sleep(wait)
+	}
+}
@@ -0,0 +1,118 @@
+package pattern_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lkendrickd/patterns/internal/pattern"
+)
+
+var (
+	errBoom = errors.New("boom")
+)
+
+// leakyPattern starts a goroutine that blocks until release is closed
+func leakyPattern(release chan struct{}) func() error {
+	return func() error {
+		go func() { <-release }()
+		return nil
+	}
+}
+
+func TestOperatorLeakCheck(t *testing.T) {
+	defer func(timeout time.Duration) { pattern.LeakTimeout = timeout }(pattern.LeakTimeout)
+	pattern.LeakTimeout = 50 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		mode     pattern.LeakMode
+		leak     bool
+		fn       func() error
+		wantLeak bool
+		wantWarn bool
+		wantErr  error
+	}{
+		{name: "OffIgnoresLeak", mode: pattern.LeakCheckOff, leak: true},
+		{name: "FailReturnsLeakError", mode: pattern.LeakCheckFail, leak: true, wantLeak: true},
+		{name: "WarnLogsLeak", mode: pattern.LeakCheckWarn, leak: true, wantWarn: true},
+		{name: "FailWithoutLeak", mode: pattern.LeakCheckFail},
+		{
+			name:     "PatternErrorKeptOverLeak",
+			mode:     pattern.LeakCheckFail,
+			fn:       func() error { go func() { time.Sleep(time.Second) }(); return errBoom },
+			wantWarn: true,
+			wantErr:  errBoom,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			defer close(release)
+
+			var logs bytes.Buffer
+			op := pattern.NewPatternOperator([]string{}, slog.New(slog.NewTextHandler(&logs, nil)))
+			op.LeakCheck = tt.mode
+
+			fn := tt.fn
+			if fn == nil {
+				fn = func() error { return nil }
+				if tt.leak {
+					fn = leakyPattern(release)
+				}
+			}
+			op.AddPattern(pattern.NewPattern("leaky", fn))
+
+			err := op.Run("leaky")
+
+			var leakErr *pattern.LeakError
+			if gotLeak := errors.As(err, &leakErr); gotLeak != tt.wantLeak {
+				t.Fatalf("Run() error = %v, want leak %v", err, tt.wantLeak)
+			}
+			if tt.wantLeak && (len(leakErr.Stacks) != 1 || !strings.Contains(leakErr.Stacks[0], "leakyPattern")) {
+				t.Errorf("LeakError stacks = %v, want the leakyPattern goroutine", leakErr.Stacks)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if !tt.wantLeak && tt.wantErr == nil && err != nil {
+				t.Errorf("Run() error = %v, want nil", err)
+			}
+			if gotWarn := strings.Contains(logs.String(), "goroutine leak detected"); gotWarn != tt.wantWarn {
+				t.Errorf("warning logged = %v, want %v\n%s", gotWarn, tt.wantWarn, logs.String())
+			}
+		})
+	}
+}
+
+func TestLeakModeSet(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    pattern.LeakMode
+		wantErr bool
+	}{
+		{"off", pattern.LeakCheckOff, false},
+		{"false", pattern.LeakCheckOff, false},
+		{"warn", pattern.LeakCheckWarn, false},
+		{"fail", pattern.LeakCheckFail, false},
+		{"true", pattern.LeakCheckFail, false},
+		{"sometimes", pattern.LeakCheckOff, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var mode pattern.LeakMode
+			err := mode.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if mode != tt.want {
+				t.Errorf("Set(%q) = %v, want %v", tt.value, mode, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package pattern
+
+// Metrics instruments the PatternOperator. Every run is counted, timed and tracked
+// while it is in flight. The collected values are rendered in the Prometheus text
+// exposition format so they can be scraped or dumped without pulling in a client library.
+// https://prometheus.io/docs/instrumenting/exposition_formats/
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// DefaultBuckets are the upper bounds in seconds of the run duration histogram
+	// they match the default buckets of the Prometheus client libraries
+	DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+)
+
+// failureKey is the label set of the failures counter
+type failureKey struct {
+	pattern   string
+	errorType string
+}
+
+// histogram is a cumulative histogram of observed values
+type histogram struct {
+	counts []uint64 // counts per bucket, the last entry is the +Inf bucket
+	sum    float64
+	count  uint64
+}
+
+// Metrics is the struct that holds the counters, histograms and gauges of the pattern runs
+type Metrics struct {
+	mu        sync.Mutex
+	buckets   []float64
+	runs      map[string]uint64
+	failures  map[failureKey]uint64
+	durations map[string]*histogram
+	inFlight  map[string]int64
+}
+
+// NewMetrics will return a new Metrics struct using the DefaultBuckets
+func NewMetrics() *Metrics {
+	return &Metrics{
+		buckets:   DefaultBuckets,
+		runs:      make(map[string]uint64),
+		failures:  make(map[failureKey]uint64),
+		durations: make(map[string]*histogram),
+		inFlight:  make(map[string]int64),
+	}
+}
+
+// start records the beginning of a pattern run and returns the function that records
+// its outcome. A nil Metrics is valid and records nothing.
+func (m *Metrics) start(pattern string) func(err error) {
+	if m == nil {
+		return func(error) {}
+	}
+
+	m.mu.Lock()
+	m.inFlight[pattern]++
+	m.mu.Unlock()
+
+	began := time.Now()
+	return func(err error) {
+		m.observe(pattern, time.Since(began), err)
+	}
+}
+
+// observe records a finished pattern run
+func (m *Metrics) observe(pattern string, elapsed time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inFlight[pattern]--
+	m.runs[pattern]++
+
+	if err != nil {
+		m.failures[failureKey{pattern: pattern, errorType: errorType(err)}]++
+	}
+
+	h, ok := m.durations[pattern]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets)+1)}
+		m.durations[pattern] = h
+	}
+
+	seconds := elapsed.Seconds()
+	// find the first bucket the value fits in, values above every bound land in +Inf
+	h.counts[sort.SearchFloat64s(m.buckets, seconds)]++
+	h.sum += seconds
+	h.count++
+}
+
+// WriteTo will write the metrics in the Prometheus text exposition format
+// a nil Metrics has nothing to report and writes nothing
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	if m == nil {
+		return 0, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+
+	b.WriteString("# HELP pattern_runs_total Total number of pattern runs.\n")
+	b.WriteString("# TYPE pattern_runs_total counter\n")
+	for _, name := range sortedKeys(m.runs) {
+		fmt.Fprintf(&b, "pattern_runs_total{pattern=%s} %d\n", quote(name), m.runs[name])
+	}
+
+	b.WriteString("# HELP pattern_run_failures_total Total number of failed pattern runs by error type.\n")
+	b.WriteString("# TYPE pattern_run_failures_total counter\n")
+	failures := make([]failureKey, 0, len(m.failures))
+	for key := range m.failures {
+		failures = append(failures, key)
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		if failures[i].pattern != failures[j].pattern {
+			return failures[i].pattern < failures[j].pattern
+		}
+		return failures[i].errorType < failures[j].errorType
+	})
+	for _, key := range failures {
+		fmt.Fprintf(&b, "pattern_run_failures_total{pattern=%s,error_type=%s} %d\n",
+			quote(key.pattern), quote(key.errorType), m.failures[key])
+	}
+
+	b.WriteString("# HELP pattern_run_duration_seconds Duration of pattern runs in seconds.\n")
+	b.WriteString("# TYPE pattern_run_duration_seconds histogram\n")
+	for _, name := range sortedKeys(m.durations) {
+		h := m.durations[name]
+		var cumulative uint64
+		for i, bound := range m.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&b, "pattern_run_duration_seconds_bucket{pattern=%s,le=%s} %d\n",
+				quote(name), quote(formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(&b, "pattern_run_duration_seconds_bucket{pattern=%s,le=\"+Inf\"} %d\n", quote(name), h.count)
+		fmt.Fprintf(&b, "pattern_run_duration_seconds_sum{pattern=%s} %s\n", quote(name), formatFloat(h.sum))
+		fmt.Fprintf(&b, "pattern_run_duration_seconds_count{pattern=%s} %d\n", quote(name), h.count)
+	}
+
+	b.WriteString("# HELP pattern_runs_in_flight Number of pattern runs currently executing.\n")
+	b.WriteString("# TYPE pattern_runs_in_flight gauge\n")
+	for _, name := range sortedKeys(m.inFlight) {
+		fmt.Fprintf(&b, "pattern_runs_in_flight{pattern=%s} %d\n", quote(name), m.inFlight[name])
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP will serve the metrics so the Metrics struct can be mounted at /metrics
+// a nil Metrics serves an empty page
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := m.WriteTo(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+/*##################################################################################
+# Helper Functions
+##################################################################################*/
+
+// errorType will return the type name of the root cause of the error
+func errorType(err error) string {
+	return fmt.Sprintf("%T", errors.Cause(err))
+}
+
+// quote will quote a label value escaping backslashes, double quotes and newlines
+func quote(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// formatFloat will format a float in the shortest representation
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// sortedKeys will return the keys of the map in sorted order so the output is stable
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,104 @@
+package pattern_test
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/pattern"
+)
+
+func TestMetricsWriteTo(t *testing.T) {
+	op := pattern.NewPatternOperator([]string{}, logger)
+	op.AddPattern(pattern.NewPattern("ok", func() error { return nil }))
+	op.AddPattern(pattern.NewPattern("fail", func() error { return errors.New("boom") }))
+
+	op.Run("ok")
+	op.Run("ok")
+	op.Run("fail")
+
+	var b strings.Builder
+	if _, err := op.Metrics.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	out := b.String()
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"RunsCounter", `pattern_runs_total{pattern="ok"} 2`},
+		{"FailedRunsCounted", `pattern_runs_total{pattern="fail"} 1`},
+		{"FailuresByErrorType", `pattern_run_failures_total{pattern="fail",error_type="*errors.errorString"} 1`},
+		{"HistogramInfBucket", `pattern_run_duration_seconds_bucket{pattern="ok",le="+Inf"} 2`},
+		{"HistogramCount", `pattern_run_duration_seconds_count{pattern="ok"} 2`},
+		{"InFlightGauge", `pattern_runs_in_flight{pattern="ok"} 0`},
+		{"TypeLine", `# TYPE pattern_run_duration_seconds histogram`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(out, tt.want+"\n") {
+				t.Errorf("WriteTo() missing %q in\n%s", tt.want, out)
+			}
+		})
+	}
+}
+
+func TestMetricsInFlight(t *testing.T) {
+	op := pattern.NewPatternOperator([]string{}, logger)
+
+	var during string
+	op.AddPattern(pattern.NewPattern("slow", func() error {
+		var b strings.Builder
+		op.Metrics.WriteTo(&b)
+		during = b.String()
+		return nil
+	}))
+	op.Run("slow")
+
+	if !strings.Contains(during, `pattern_runs_in_flight{pattern="slow"} 1`) {
+		t.Errorf("in flight gauge not raised during the run\n%s", during)
+	}
+}
+
+func TestMetricsServeHTTP(t *testing.T) {
+	op := pattern.NewPatternOperator([]string{}, logger)
+	op.AddPattern(pattern.NewPattern("ok", func() error { return nil }))
+	op.Run("ok")
+
+	rec := httptest.NewRecorder()
+	op.Metrics.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want Prometheus text format", got)
+	}
+
+	body, _ := io.ReadAll(rec.Body)
+	if !strings.Contains(string(body), `pattern_runs_total{pattern="ok"} 1`) {
+		t.Errorf("body missing runs counter\n%s", body)
+	}
+}
+
+func TestMetricsNilDisabled(t *testing.T) {
+	op := pattern.NewPatternOperator([]string{}, logger)
+	op.Metrics = nil
+	op.AddPattern(pattern.NewPattern("ok", func() error { return nil }))
+
+	if err := op.Run("ok"); err != nil {
+		t.Errorf("Run() with nil Metrics error = %v", err)
+	}
+
+	var b strings.Builder
+	if n, err := op.Metrics.WriteTo(&b); n != 0 || err != nil {
+		t.Errorf("WriteTo() with nil Metrics = %d, %v, want nothing written", n, err)
+	}
+
+	rec := httptest.NewRecorder()
+	op.Metrics.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != 200 || rec.Body.Len() != 0 {
+		t.Errorf("ServeHTTP() with nil Metrics = %d %q, want an empty page", rec.Code, rec.Body)
+	}
+}
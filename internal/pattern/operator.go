@@ -1,6 +1,8 @@
 package pattern
 
 import (
+	"context"
+	"io"
 	"log/slog"
 
 	"github.com/pkg/errors"
@@ -15,6 +17,13 @@ type PatternOperator struct {
 	Patterns map[string]Pattern // Patterns are a map of type string to Pattern
 	Types    []string           // Types are the types of patterns that can be run by string name
 	Logger   *slog.Logger
+	Metrics  *Metrics  // Metrics are the run statistics of the patterns, nil disables them
+	Tracer   *Tracer   // Tracer creates a span for every run, nil disables tracing
+	Output   io.Writer // Output is injected into every run, nil leaves the output of the context
+	// LeakCheck is the goroutine leak detection mode of the runs, off by default
+	LeakCheck LeakMode
+	// Params are injected into every run, nil leaves the params of the context
+	Params Params
 }
 
 // NewPatternOperator will return a new PatternOperator struct
@@ -23,6 +32,7 @@ func NewPatternOperator(patternTypes []string, logger *slog.Logger) *PatternOper
 		Logger:   logger,
 		Patterns: make(map[string]Pattern),
 		Types:    patternTypes,
+		Metrics:  NewMetrics(),
 	}
 }
 
@@ -34,7 +44,7 @@ func (p *PatternOperator) AddPattern(pattern Pattern) error {
 	}
 
 	// if the patter function is missing then return an error
-	if pattern.PatternFunc == nil {
+	if pattern.PatternFunc == nil && pattern.PatternContextFunc == nil {
 		return ErrAddPattern
 	}
 
@@ -57,14 +67,71 @@ func (p *PatternOperator) RemovePattern(pattern string) error {
 
 // Run will run the pattern function
 func (p *PatternOperator) Run(pattern string) error {
+	return p.RunContext(context.Background(), pattern)
+}
+
+// RunContext will run the pattern function passing the context down to the pattern
+func (p *PatternOperator) RunContext(ctx context.Context, pattern string) error {
 	// check the Patterns map to see if the requested pattern exists
-	if _, ok := p.Patterns[pattern]; !ok {
+	run, ok := p.Patterns[pattern]
+	if !ok {
 		return errors.New("pattern does not exist")
 	}
 
+	// inject the output writer so the pattern does not write straight to stdout
+	if p.Output != nil {
+		ctx = WithOutput(ctx, p.Output)
+	}
+	if p.Params != nil {
+		ctx = WithParams(ctx, p.Params)
+	}
+
+	// open the run span so the pattern can create child spans from the context
+	var span *Span
+	if p.Tracer != nil {
+		ctx, span = p.Tracer.Start(ctx, "pattern.run")
+		span.SetAttribute("pattern", pattern)
+	}
+
+	// snapshot the goroutines before the run to find the ones it leaves behind
+	var goroutines GoroutineSnapshot
+	if p.LeakCheck != LeakCheckOff {
+		goroutines = SnapshotGoroutines()
+	}
+
+	// record the run in the metrics
+	done := p.Metrics.start(pattern)
+
 	// run the pattern function
-	if err := p.Patterns[pattern].PatternFunc(); err != nil {
+	err := run.RunContext(ctx)
+
+	if goroutines != nil {
+		err = p.checkLeaks(pattern, goroutines, err)
+	}
+	done(err)
+
+	if spanErr := span.Finish(err); spanErr != nil && p.Logger != nil {
+		p.Logger.Error("could not export span", "error", spanErr)
+	}
+
+	return err
+}
+
+// checkLeaks will report the goroutines the run left behind according to the LeakCheck mode
+// a leak only fails the run if the pattern itself succeeded so the pattern error is kept
+func (p *PatternOperator) checkLeaks(pattern string, before GoroutineSnapshot, err error) error {
+	stacks := before.Leaked(LeakTimeout)
+	if len(stacks) == 0 {
 		return err
 	}
-	return nil
+
+	leak := &LeakError{Pattern: pattern, Stacks: stacks}
+	if p.LeakCheck == LeakCheckFail && err == nil {
+		return leak
+	}
+
+	if p.Logger != nil {
+		p.Logger.Warn("goroutine leak detected", "pattern", pattern, "count", len(stacks), "stacks", stacks)
+	}
+	return err
 }
@@ -0,0 +1,27 @@
+package pattern
+
+// Patterns write their output to the io.Writer carried by the context instead of writing
+// straight to stdout. The PatternOperator injects its Output into every run which lets
+// tests capture and compare what a pattern prints.
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// outputKey is the context key for the output writer
+type outputKey struct{}
+
+// WithOutput will return a context carrying the writer the pattern output goes to
+func WithOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, outputKey{}, w)
+}
+
+// Output will return the writer of the context or os.Stdout if the context has none
+func Output(ctx context.Context) io.Writer {
+	if w, ok := ctx.Value(outputKey{}).(io.Writer); ok && w != nil {
+		return w
+	}
+	return os.Stdout
+}
@@ -0,0 +1,56 @@
+package pattern
+
+// Params are the key value parameters handed to a pattern run. They are passed on the
+// command line as repeated -param key=value flags and reach the pattern through the context.
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// paramsKey is the context key for the params
+type paramsKey struct{}
+
+// Params is the map of parameter names to values and implements flag.Value
+type Params map[string]string
+
+// String will return the params as comma separated key=value pairs in key order
+func (p Params) String() string {
+	pairs := make([]string, 0, len(p))
+	for _, key := range sortedKeys(p) {
+		pairs = append(pairs, key+"="+p[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set will parse a key=value pair into the params so the flag can be repeated
+func (p Params) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("param %q must be in the form key=value", value)
+	}
+	p[key] = val
+	return nil
+}
+
+// Get will return the value of the key or the fallback if the key is not set
+func (p Params) Get(key string, fallback string) string {
+	if value, ok := p[key]; ok {
+		return value
+	}
+	return fallback
+}
+
+// WithParams will return a context carrying the params of the run
+func WithParams(ctx context.Context, params Params) context.Context {
+	return context.WithValue(ctx, paramsKey{}, params)
+}
+
+// ParamsFrom will return the params of the context or empty params if the context has none
+func ParamsFrom(ctx context.Context) Params {
+	if params, ok := ctx.Value(paramsKey{}).(Params); ok && params != nil {
+		return params
+	}
+	return Params{}
+}
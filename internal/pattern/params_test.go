@@ -0,0 +1,66 @@
+package pattern_test
+
+import (
+	"context"
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/pattern"
+)
+
+func TestParamsFlag(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{"Single", []string{"-param", "source=csv:records.csv"}, "source=csv:records.csv", false},
+		{"Repeated", []string{"-param", "source=x", "-param", "field=2"}, "field=2,source=x", false},
+		{"ValueWithEquals", []string{"-param", "expr=a=b"}, "expr=a=b", false},
+		{"EmptyValue", []string{"-param", "key="}, "key=", false},
+		{"MissingEquals", []string{"-param", "source"}, "", true},
+		{"MissingKey", []string{"-param", "=value"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := pattern.Params{}
+			fs := flag.NewFlagSet(tt.name, flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			fs.Var(params, "param", "")
+
+			err := fs.Parse(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && params.String() != tt.want {
+				t.Errorf("params = %q, want %q", params.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestOperatorParams(t *testing.T) {
+	op := pattern.NewPatternOperator([]string{}, logger)
+	op.Params = pattern.Params{"source": "csv:records.csv"}
+
+	var got, fallback string
+	op.AddPattern(pattern.NewPatternContext("params", func(ctx context.Context) error {
+		got = pattern.ParamsFrom(ctx).Get("source", "")
+		fallback = pattern.ParamsFrom(ctx).Get("field", "0")
+		return nil
+	}))
+
+	if err := op.Run("params"); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got != "csv:records.csv" || fallback != "0" {
+		t.Errorf("params got source %q field %q", got, fallback)
+	}
+
+	if params := pattern.ParamsFrom(context.Background()); len(params) != 0 {
+		t.Errorf("ParamsFrom() without params = %v, want empty", params)
+	}
+}
@@ -4,6 +4,7 @@ package pattern
 // It follows the command pattern.
 
 import (
+	"context"
 	"errors"
 )
 
@@ -13,10 +14,22 @@ type Pattern struct {
 	Pattern string
 	// PatternFunc is the function to run
 	PatternFunc func() error
+	// PatternContextFunc is the context aware function to run
+	// when it is set it takes precedence over PatternFunc
+	PatternContextFunc func(ctx context.Context) error
 }
 
 // Run will run the pattern function
 func (p *Pattern) Run() error {
+	return p.RunContext(context.Background())
+}
+
+// RunContext will run the pattern function passing the context to the context aware function
+func (p *Pattern) RunContext(ctx context.Context) error {
+	if p.PatternContextFunc != nil {
+		return p.PatternContextFunc(ctx)
+	}
+
 	if p.PatternFunc == nil {
 		return errors.New("pattern function is nil")
 	}
@@ -36,6 +49,14 @@ func NewPattern(pattern string, patternFunc func() error) Pattern {
 	}
 }
 
+// NewPatternContext will return a new pattern struct with a context aware function
+func NewPatternContext(pattern string, patternFunc func(ctx context.Context) error) Pattern {
+	return Pattern{
+		Pattern:            pattern,
+		PatternContextFunc: patternFunc,
+	}
+}
+
 // PatternExist will check if the pattern exists
 func (p *PatternOperator) PatternExist(pattern string) bool {
 	// check if the pattern even exists
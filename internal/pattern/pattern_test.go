@@ -1,10 +1,13 @@
 package pattern_test
 
 import (
+	"context"
 	"errors"
 	"io"
 	"log/slog"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/lkendrickd/patterns/internal/pattern"
@@ -42,6 +45,14 @@ func TestRun(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "ContextFuncTakesPrecedence",
+			pattern: pattern.Pattern{
+				PatternFunc:        func() error { return errors.New("error") },
+				PatternContextFunc: func(ctx context.Context) error { return nil },
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -270,3 +281,24 @@ func TestOperatorRun(t *testing.T) {
 		})
 	}
 }
+
+func TestOperatorOutput(t *testing.T) {
+	var buf strings.Builder
+	op := pattern.NewPatternOperator([]string{}, logger)
+	op.Output = &buf
+	op.AddPattern(pattern.NewPatternContext("write", func(ctx context.Context) error {
+		_, err := io.WriteString(pattern.Output(ctx), "written")
+		return err
+	}))
+
+	if err := op.Run("write"); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if buf.String() != "written" {
+		t.Errorf("Output got %q, want %q", buf.String(), "written")
+	}
+
+	if got := pattern.Output(context.Background()); got != os.Stdout {
+		t.Errorf("Output() without writer = %v, want os.Stdout", got)
+	}
+}
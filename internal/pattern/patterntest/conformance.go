@@ -0,0 +1,150 @@
+package patterntest
+
+// The conformance kit checks the behavior every pattern is expected to have. A pattern
+// package or the application registering the pattern calls patterntest.Run(t, p) and gets
+// a subtest per check. Run the tests with -race so the concurrency check can catch data races.
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lkendrickd/patterns/internal/pattern"
+)
+
+const (
+	// DefaultConcurrency is the number of concurrent runs of the concurrency check
+	DefaultConcurrency = 8
+	// DefaultDeadline is the time a pattern has to return once its context is done
+	DefaultDeadline = time.Second
+)
+
+// Suite is the struct that holds the settings of the conformance checks
+type Suite struct {
+	// Concurrency is the number of runs started at the same time
+	Concurrency int
+	// Deadline is how long a pattern may keep running after its context is cancelled
+	Deadline time.Duration
+}
+
+// Run will run the conformance checks against the pattern with the default settings
+func Run(t *testing.T, p pattern.Pattern) {
+	t.Helper()
+	Suite{
+		Concurrency: DefaultConcurrency,
+		Deadline:    DefaultDeadline,
+	}.Run(t, p)
+}
+
+// Run will run the conformance checks against the pattern
+func (s Suite) Run(t *testing.T, p pattern.Pattern) {
+	t.Helper()
+
+	t.Run("Metadata", func(t *testing.T) {
+		checkMetadata(t, p)
+	})
+	t.Run("DefaultRun", func(t *testing.T) {
+		if _, err := RunOutput(context.Background(), p); err != nil {
+			t.Errorf("run with default params error = %v", err)
+		}
+	})
+	t.Run("Concurrent", func(t *testing.T) {
+		checkConcurrent(t, p, s.Concurrency)
+	})
+	t.Run("Cancellation", func(t *testing.T) {
+		checkCancellation(t, p, s.Deadline)
+	})
+	t.Run("GoroutineLeaks", func(t *testing.T) {
+		checkGoroutines(t, p)
+	})
+}
+
+// checkMetadata checks the pattern has a usable name and function and can be registered
+func checkMetadata(t *testing.T, p pattern.Pattern) {
+	t.Helper()
+
+	if p.Pattern == "" {
+		t.Fatalf("pattern name is empty")
+	}
+	if strings.ToLower(p.Pattern) != p.Pattern || strings.ContainsAny(p.Pattern, " \t\n") {
+		t.Errorf("pattern name %q must be lower case without whitespace so it can be passed to -pattern", p.Pattern)
+	}
+	if p.PatternFunc == nil && p.PatternContextFunc == nil {
+		t.Fatalf("pattern %q has no function", p.Pattern)
+	}
+
+	op := pattern.NewPatternOperator([]string{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := op.AddPattern(p); err != nil {
+		t.Fatalf("AddPattern() error = %v", err)
+	}
+	if !op.PatternExist(p.Pattern) {
+		t.Errorf("pattern %q not found after registering it", p.Pattern)
+	}
+}
+
+// checkConcurrent runs the pattern n times at once and reports every failed run
+func checkConcurrent(t *testing.T, p pattern.Pattern, n int) {
+	t.Helper()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	start := make(chan struct{})
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// start every run together to give the race detector the best chance
+			<-start
+			if _, err := RunOutput(context.Background(), p); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent run error = %v", err)
+	}
+}
+
+// checkCancellation runs the pattern with an already cancelled context and fails if it
+// does not return within the deadline. An error from the run is expected and ignored.
+func checkCancellation(t *testing.T, p pattern.Pattern, deadline time.Duration) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		RunOutput(ctx, p)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(deadline):
+		t.Errorf("pattern %q still running %s after its context was cancelled", p.Pattern, deadline)
+	}
+}
+
+// checkGoroutines fails with the stacks of the goroutines the pattern leaves running
+func checkGoroutines(t *testing.T, p pattern.Pattern) {
+	t.Helper()
+
+	before := pattern.SnapshotGoroutines()
+	if _, err := RunOutput(context.Background(), p); err != nil {
+		t.Fatalf("run error = %v", err)
+	}
+
+	if stacks := before.Leaked(pattern.LeakTimeout); len(stacks) > 0 {
+		t.Errorf("%v", &pattern.LeakError{Pattern: p.Pattern, Stacks: stacks})
+	}
+}
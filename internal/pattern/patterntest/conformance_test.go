@@ -0,0 +1,22 @@
+package patterntest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/pattern"
+	"github.com/lkendrickd/patterns/internal/pattern/patterntest"
+)
+
+func TestRunConformance(t *testing.T) {
+	patterntest.Run(t, pattern.NewPatternContext("conforming", func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		fmt.Fprintln(pattern.Output(ctx), "conforming")
+		return nil
+	}))
+}
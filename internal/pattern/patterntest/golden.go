@@ -0,0 +1,87 @@
+// Package patterntest provides helpers for testing patterns. The golden file helpers
+// capture the output of a pattern run and compare it against a file kept in testdata.
+// Run the tests with the -update flag to rewrite the golden files:
+//
+//	go test ./cmd -update
+package patterntest
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/pattern"
+)
+
+var (
+	// update is the flag to rewrite the golden files with the current output
+	update = flag.Bool("update", false, "update the golden files in testdata")
+
+	uuidRegexp      = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	timestampRegexp = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`)
+)
+
+// Normalizer rewrites the parts of the output that change on every run
+type Normalizer func(output []byte) []byte
+
+// NormalizeUUIDs replaces every UUID with a numbered placeholder in order of appearance.
+// The same UUID gets the same placeholder so the output still shows which ids are equal.
+func NormalizeUUIDs(output []byte) []byte {
+	seen := make(map[string]string)
+	return uuidRegexp.ReplaceAllFunc(output, func(id []byte) []byte {
+		placeholder, ok := seen[string(id)]
+		if !ok {
+			placeholder = fmt.Sprintf("<uuid-%d>", len(seen)+1)
+			seen[string(id)] = placeholder
+		}
+		return []byte(placeholder)
+	})
+}
+
+// NormalizeTimestamps replaces every RFC 3339 like timestamp with a placeholder
+func NormalizeTimestamps(output []byte) []byte {
+	return timestampRegexp.ReplaceAll(output, []byte("<timestamp>"))
+}
+
+// RunOutput will run the pattern with a buffer injected as its output and return what it wrote
+func RunOutput(ctx context.Context, p pattern.Pattern) ([]byte, error) {
+	var buf bytes.Buffer
+	err := p.RunContext(pattern.WithOutput(ctx, &buf))
+	return buf.Bytes(), err
+}
+
+// Golden will compare the normalized output with testdata/<name>.golden
+// when the -update flag is set the golden file is written instead
+func Golden(t testing.TB, name string, got []byte, normalizers ...Normalizer) {
+	t.Helper()
+
+	for _, normalize := range normalizers {
+		got = normalize(got)
+	}
+
+	path := filepath.Join("testdata", name+".golden")
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("could not create testdata: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("could not update golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read golden file, run with -update to create it: %v", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("output does not match %s\n--- got ---\n%s\n--- want ---\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,69 @@
+package patterntest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/pattern"
+	"github.com/lkendrickd/patterns/internal/pattern/patterntest"
+)
+
+func TestNormalizeUUIDs(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"NoUUID", "foo", "foo"},
+		{"SingleUUID", "id: 0cc540bb-4878-4946-b7df-38289630ed2f", "id: <uuid-1>"},
+		{
+			"RepeatedUUIDKeepsPlaceholder",
+			"0cc540bb-4878-4946-b7df-38289630ed2f 97c9c399-e9cb-47bb-b98e-f205521894f7 0cc540bb-4878-4946-b7df-38289630ed2f",
+			"<uuid-1> <uuid-2> <uuid-1>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(patterntest.NormalizeUUIDs([]byte(tt.input))); got != tt.want {
+				t.Errorf("NormalizeUUIDs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTimestamps(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"RFC3339Nano", "at 2026-10-19T08:20:31.669202832Z done", "at <timestamp> done"},
+		{"RFC3339Offset", "2026-10-19T08:20:31+02:00", "<timestamp>"},
+		{"SpaceSeparated", "2026-10-19 08:20:31", "<timestamp>"},
+		{"NotATimestamp", "2026-10-19", "2026-10-19"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(patterntest.NormalizeTimestamps([]byte(tt.input))); got != tt.want {
+				t.Errorf("NormalizeTimestamps() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunOutputGolden(t *testing.T) {
+	p := pattern.NewPatternContext("hello", func(ctx context.Context) error {
+		fmt.Fprintln(pattern.Output(ctx), "hello 0cc540bb-4878-4946-b7df-38289630ed2f")
+		return nil
+	})
+
+	got, err := patterntest.RunOutput(context.Background(), p)
+	if err != nil {
+		t.Fatalf("RunOutput() error = %v", err)
+	}
+
+	patterntest.Golden(t, "hello", got, patterntest.NormalizeUUIDs)
+}
@@ -0,0 +1,258 @@
+package pattern
+
+// This is a lightweight tracing abstraction for the pattern runs. Every run creates a span
+// and spans propagate through the context.Context so a pattern can open child spans for
+// its own steps. Finished spans are handed to an Exporter which writes them as JSON lines
+// or in the Chrome trace event format that can be loaded in chrome://tracing.
+// https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// spanKey and tracerKey are the context keys for the active span and tracer
+type (
+	spanKey   struct{}
+	tracerKey struct{}
+)
+
+// Exporter is the interface that receives the finished spans
+type Exporter interface {
+	// ExportSpan is called once for every finished span
+	ExportSpan(span *Span) error
+	// Close flushes anything buffered by the exporter
+	Close() error
+}
+
+// Tracer is the struct that creates spans and hands them to the exporter once finished
+type Tracer struct {
+	mu       sync.Mutex
+	exporter Exporter
+}
+
+// NewTracer will return a new Tracer struct exporting to the exporter
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{
+		exporter: exporter,
+	}
+}
+
+// Start will start a new span as a child of the span in the context if there is one
+// and return a context carrying both the tracer and the new span
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	span := &Span{
+		SpanID:     uuid.NewString(),
+		Name:       name,
+		StartTime:  time.Now(),
+		Goroutine:  goroutineID(),
+		Attributes: make(map[string]string),
+		tracer:     t,
+	}
+
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.TraceID = parent.TraceID
+		span.ParentID = parent.SpanID
+	} else {
+		span.TraceID = uuid.NewString()
+	}
+
+	ctx = context.WithValue(ctx, tracerKey{}, t)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Close will close the exporter of the tracer
+func (t *Tracer) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.exporter.Close()
+}
+
+// export will hand the finished span to the exporter
+func (t *Tracer) export(span *Span) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.exporter.ExportSpan(span)
+}
+
+// Span is the struct that holds a single timed operation
+type Span struct {
+	TraceID    string            `json:"trace_id"`
+	SpanID     string            `json:"span_id"`
+	ParentID   string            `json:"parent_id,omitempty"`
+	Name       string            `json:"name"`
+	StartTime  time.Time         `json:"start"`
+	EndTime    time.Time         `json:"end"`
+	Goroutine  int64             `json:"goroutine"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Error      string            `json:"error,omitempty"`
+
+	mu     sync.Mutex
+	tracer *Tracer
+}
+
+// SetAttribute will set an attribute on the span. A nil span is valid and records nothing.
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Attributes[key] = value
+}
+
+// Finish will end the span recording the error if there is one and export it.
+// A nil span is valid and records nothing.
+func (s *Span) Finish(err error) error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.EndTime = time.Now()
+	if err != nil {
+		s.Error = err.Error()
+	}
+	s.mu.Unlock()
+
+	return s.tracer.export(s)
+}
+
+// Duration will return how long the span took
+func (s *Span) Duration() time.Duration {
+	return s.EndTime.Sub(s.StartTime)
+}
+
+// SpanFromContext will return the active span of the context or nil if there is none
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// StartSpan will start a child span using the tracer carried by the context. When the
+// context has no tracer the returned span is nil which is safe to use and records nothing.
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	tracer, ok := ctx.Value(tracerKey{}).(*Tracer)
+	if !ok || tracer == nil {
+		return ctx, nil
+	}
+	return tracer.Start(ctx, name)
+}
+
+/*##################################################################################
+# Exporters
+##################################################################################*/
+
+// JSONLinesExporter writes every finished span as a single line of JSON
+type JSONLinesExporter struct {
+	encoder *json.Encoder
+}
+
+// NewJSONLinesExporter will return a new JSONLinesExporter writing to w
+func NewJSONLinesExporter(w io.Writer) *JSONLinesExporter {
+	return &JSONLinesExporter{
+		encoder: json.NewEncoder(w),
+	}
+}
+
+// ExportSpan will write the span as a line of JSON
+func (e *JSONLinesExporter) ExportSpan(span *Span) error {
+	return e.encoder.Encode(span)
+}
+
+// Close is a no-op as the lines are written as the spans finish
+func (e *JSONLinesExporter) Close() error {
+	return nil
+}
+
+// chromeEvent is a complete event of the Chrome trace event format
+type chromeEvent struct {
+	Name string            `json:"name"`
+	Cat  string            `json:"cat"`
+	Ph   string            `json:"ph"`
+	Ts   int64             `json:"ts"`  // start in microseconds
+	Dur  int64             `json:"dur"` // duration in microseconds
+	Pid  int               `json:"pid"`
+	Tid  int64             `json:"tid"`
+	Args map[string]string `json:"args,omitempty"`
+}
+
+// ChromeTraceExporter buffers the finished spans and writes them in the Chrome trace
+// event format on Close. Every goroutine gets its own row in the timeline.
+type ChromeTraceExporter struct {
+	w      io.Writer
+	events []chromeEvent
+}
+
+// NewChromeTraceExporter will return a new ChromeTraceExporter writing to w
+func NewChromeTraceExporter(w io.Writer) *ChromeTraceExporter {
+	return &ChromeTraceExporter{
+		w: w,
+	}
+}
+
+// ExportSpan will buffer the span as a complete event
+func (e *ChromeTraceExporter) ExportSpan(span *Span) error {
+	args := map[string]string{
+		"trace_id": span.TraceID,
+		"span_id":  span.SpanID,
+	}
+	if span.ParentID != "" {
+		args["parent_id"] = span.ParentID
+	}
+	if span.Error != "" {
+		args["error"] = span.Error
+	}
+	for key, value := range span.Attributes {
+		args[key] = value
+	}
+
+	e.events = append(e.events, chromeEvent{
+		Name: span.Name,
+		Cat:  "pattern",
+		Ph:   "X",
+		Ts:   span.StartTime.UnixMicro(),
+		Dur:  span.Duration().Microseconds(),
+		Pid:  1,
+		Tid:  span.Goroutine,
+		Args: args,
+	})
+	return nil
+}
+
+// Close will write the buffered events as a trace event JSON object
+func (e *ChromeTraceExporter) Close() error {
+	events := e.events
+	if events == nil {
+		events = []chromeEvent{}
+	}
+	return json.NewEncoder(e.w).Encode(struct {
+		TraceEvents []chromeEvent `json:"traceEvents"`
+	}{events})
+}
+
+/*##################################################################################
+# Helper Functions
+##################################################################################*/
+
+// goroutineID will return the id of the calling goroutine parsed from its stack header
+// which looks like "goroutine 18 [running]:"
+func goroutineID() int64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i > 0 {
+		buf = buf[:i]
+	}
+	id, _ := strconv.ParseInt(string(buf), 10, 64)
+	return id
+}
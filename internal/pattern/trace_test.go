@@ -0,0 +1,152 @@
+package pattern_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/pattern"
+)
+
+// recordingExporter keeps the finished spans in memory
+type recordingExporter struct {
+	spans []*pattern.Span
+}
+
+func (r *recordingExporter) ExportSpan(span *pattern.Span) error {
+	r.spans = append(r.spans, span)
+	return nil
+}
+
+func (r *recordingExporter) Close() error { return nil }
+
+func TestOperatorRunContextSpans(t *testing.T) {
+	exporter := &recordingExporter{}
+	op := pattern.NewPatternOperator([]string{}, logger)
+	op.Tracer = pattern.NewTracer(exporter)
+
+	op.AddPattern(pattern.NewPatternContext("traced", func(ctx context.Context) error {
+		_, child := pattern.StartSpan(ctx, "step")
+		child.SetAttribute("step", "1")
+		return child.Finish(nil)
+	}))
+	op.AddPattern(pattern.NewPattern("failing", func() error { return errors.New("boom") }))
+
+	if err := op.Run("traced"); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if err := op.Run("failing"); err == nil {
+		t.Fatalf("Run() expected an error")
+	}
+
+	if len(exporter.spans) != 3 {
+		t.Fatalf("exported %d spans, want 3", len(exporter.spans))
+	}
+
+	child, run, failed := exporter.spans[0], exporter.spans[1], exporter.spans[2]
+
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"ChildFinishesFirst", child.Name == "step" && run.Name == "pattern.run"},
+		{"ChildParentIsRun", child.ParentID == run.SpanID},
+		{"ChildSharesTrace", child.TraceID == run.TraceID},
+		{"RunHasPatternAttribute", run.Attributes["pattern"] == "traced"},
+		{"ChildHasAttribute", child.Attributes["step"] == "1"},
+		{"FailedRunRecordsError", failed.Error == "boom"},
+		{"RunsAreSeparateTraces", failed.TraceID != run.TraceID},
+		{"RunIsRoot", run.ParentID == ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.ok {
+				t.Errorf("%s failed", tt.name)
+			}
+		})
+	}
+}
+
+func TestStartSpanWithoutTracer(t *testing.T) {
+	ctx, span := pattern.StartSpan(context.Background(), "untraced")
+	if span != nil {
+		t.Errorf("StartSpan() without tracer = %v, want nil", span)
+	}
+
+	// a nil span must be safe to use
+	span.SetAttribute("key", "value")
+	if err := span.Finish(nil); err != nil {
+		t.Errorf("Finish() on nil span error = %v", err)
+	}
+
+	if pattern.SpanFromContext(ctx) != nil {
+		t.Errorf("SpanFromContext() want nil")
+	}
+}
+
+func TestJSONLinesExporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := pattern.NewTracer(pattern.NewJSONLinesExporter(&buf))
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	_, child := pattern.StartSpan(ctx, "child")
+	child.Finish(nil)
+	parent.Finish(nil)
+	tracer.Close()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+
+	var got struct {
+		Name     string `json:"name"`
+		ParentID string `json:"parent_id"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.Name != "child" || got.ParentID != parent.SpanID {
+		t.Errorf("first line = %+v, want child of %s", got, parent.SpanID)
+	}
+}
+
+func TestChromeTraceExporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := pattern.NewTracer(pattern.NewChromeTraceExporter(&buf))
+
+	_, span := tracer.Start(context.Background(), "run")
+	span.SetAttribute("pattern", "foo")
+	span.Finish(nil)
+
+	if buf.Len() != 0 {
+		t.Errorf("events written before Close()")
+	}
+	if err := tracer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var got struct {
+		TraceEvents []struct {
+			Name string            `json:"name"`
+			Ph   string            `json:"ph"`
+			Tid  int64             `json:"tid"`
+			Args map[string]string `json:"args"`
+		} `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if len(got.TraceEvents) != 1 {
+		t.Fatalf("got %d events, want 1", len(got.TraceEvents))
+	}
+	event := got.TraceEvents[0]
+	if event.Name != "run" || event.Ph != "X" || event.Tid == 0 || event.Args["pattern"] != "foo" {
+		t.Errorf("event = %+v", event)
+	}
+}
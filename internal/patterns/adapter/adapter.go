@@ -1,7 +1,8 @@
 package adapter
 
 import (
-	"github.com/google/uuid"
+	"log/slog"
+
 	"github.com/pkg/errors"
 )
 
@@ -11,38 +12,207 @@ type APIAdapter interface {
 	ListEntries() map[string]string
 }
 
-// Adapter is the struct that implements the APIAdapter interface
+// Entry is a single key value pair of the modern API
+type Entry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// RecordsAdapter is the struct that implements the APIAdapter interface
 // this adapter is one of the key points of the adapter pattern
 // it wraps both the legacy and modern APIs so they can be used
-// interchangeably
-type Adapter struct {
-	legacy LegacyAPI
-	modern ModernAPI
+// interchangeably. It is a specialization of the generic Adapter
+// converting the legacy string records into modern entries.
+type RecordsAdapter struct {
+	legacy    LegacyAPI
+	modern    ModernAPI
+	keys      KeyStrategy
+	errorMode ErrorMode
+	pipeline  Pipeline
+	auditSink AuditSink
+	logger    *slog.Logger
+	// pipelineResult is the pipeline run of the current conversion, it traces the
+	// converted values back to their legacy records for the audit
+	pipelineResult *PipelineResult
+}
+
+// NewAdapter will return a new RecordsAdapter struct configured by the options
+// WithKeyStrategy, WithErrorMode, WithPipeline, WithAuditSink and WithLogger
+func NewAdapter(legacy LegacyAPI, modern ModernAPI, opts ...AdapterOption) *RecordsAdapter {
+	o := newOptions(opts, AdapterOption.applyAdapter)
+	return &RecordsAdapter{
+		legacy:    legacy,
+		modern:    modern,
+		keys:      o.keys,
+		errorMode: o.errorMode,
+		pipeline:  o.pipeline,
+		auditSink: o.auditSink,
+		logger:    o.logger,
+	}
 }
 
-// NewAdapter will return a new Adapter struct
-func NewAdapter(legacy LegacyAPI, modern ModernAPI) *Adapter {
-	return &Adapter{
-		legacy: legacy,
-		modern: modern,
+// DryRunPipeline will run the pipeline over the legacy records without converting them
+// the result reports the before and after of every legacy record
+func (a *RecordsAdapter) DryRunPipeline() (*PipelineResult, error) {
+	records, err := a.legacyRecords()
+	if err != nil {
+		return nil, err
 	}
+	return a.pipeline.Run(records)
 }
 
 // ConvertRecords will convert the records from the legacy API to the modern API
-func (a *Adapter) ConvertRecords() error {
-	if len(a.legacy.Records()) == 0 {
-		return errors.New("no records to convert")
+// a record the modern API rejects is handled according to the error mode
+func (a *RecordsAdapter) ConvertRecords() error {
+	remover, canRemove := a.modern.(EntryRemover)
+	if a.errorMode == Transactional && !canRemove {
+		return errors.New("transactional mode requires a modern API that can remove entries")
+	}
+
+	var (
+		index  int
+		failed ConversionErrors
+		undo   []func() error // undo restores the entries written so far in reverse order
+	)
+
+	sink := func(entry Entry) error {
+		defer func() { index++ }()
+
+		var (
+			previous string
+			existed  bool
+		)
+		if a.errorMode == Transactional {
+			previous, existed = lookupEntry(a.modern, entry.Key)
+		}
+
+		err := a.addEntry(entry)
+		if err == nil {
+			a.audit(index, entry, OutcomeConverted, nil)
+			if a.errorMode == Transactional {
+				written := index
+				undo = append(undo, func() error {
+					var err error
+					if existed {
+						err = a.modern.AddEntry(entry.Key, previous)
+					} else {
+						err = remover.RemoveEntry(entry.Key)
+					}
+					if err == nil {
+						a.audit(written, entry, OutcomeRolledBack, nil)
+					}
+					return err
+				})
+			}
+			return nil
+		}
+
+		a.audit(index, entry, OutcomeFailed, err)
+		recordErr := a.recordError(index, entry.Value, err)
+		a.logger.Warn("record failed to convert", "index", recordErr.Index, "mode", a.errorMode, "error", err)
+		if a.errorMode == SkipAndCollect {
+			failed = append(failed, recordErr)
+			return nil
+		}
+		return recordErr
 	}
 
-	for _, record := range a.legacy.Records() {
-		if err := a.modern.AddEntry(uuid.NewString(), record); err != nil {
-			return err
+	if _, err := New(a.records, a.toEntry, sink).Convert(); err != nil {
+		if a.errorMode == Transactional {
+			a.logger.Warn("rolling back the conversion", "records", len(undo))
+			return rollback(undo, err)
 		}
+		return err
+	}
+
+	if index == 0 {
+		return errors.New("no records to convert")
+	}
+	a.logger.Info("records converted", "records", index, "failed", len(failed))
+	if len(failed) > 0 {
+		return failed
 	}
 	return nil
 }
 
 // ListEntries will list the entries from the modern API
-func (a *Adapter) ListEntries() map[string]string {
+func (a *RecordsAdapter) ListEntries() map[string]string {
 	return a.modern.Entries()
 }
+
+// records is the Source of the adapter reading the records of the legacy API
+// the records are streamed when the legacy API implements RecordStreamer
+// and there is no pipeline to pass them through first
+func (a *RecordsAdapter) records(yield func(string) bool) error {
+	a.pipelineResult = nil
+	if a.pipeline == nil {
+		return a.legacySource()(yield)
+	}
+
+	result, err := a.DryRunPipeline()
+	if result != nil {
+		a.pipelineResult = result
+		a.auditPipeline(result, err)
+	}
+	if err != nil {
+		return err
+	}
+	return SliceSource(result.Values())(yield)
+}
+
+// legacySource will return the Source of the raw legacy records
+func (a *RecordsAdapter) legacySource() Source[string] {
+	if streamer, ok := a.legacy.(RecordStreamer); ok {
+		return streamer.StreamRecords
+	}
+	return SliceSource(a.legacy.Records())
+}
+
+// legacyRecords will read every raw legacy record
+func (a *RecordsAdapter) legacyRecords() ([]string, error) {
+	var records []string
+	err := a.legacySource()(func(record string) bool {
+		records = append(records, record)
+		return true
+	})
+	return records, err
+}
+
+// origin will return the index and record of the legacy record the converted value at the
+// index came from, with a pipeline the values are counted after the pipeline and a stage
+// such as Split turns one legacy record into several values
+func (a *RecordsAdapter) origin(index int, value string) (int, string) {
+	if r := a.pipelineResult; r != nil && index < len(r.Items) {
+		origin := r.Items[index].Origins[0]
+		return origin, r.Records[origin]
+	}
+	return index, value
+}
+
+// recordError will return the RecordError of the converted value at the index naming the
+// legacy record it came from
+func (a *RecordsAdapter) recordError(index int, value string, err error) *RecordError {
+	index, record := a.origin(index, value)
+	return &RecordError{Index: index, Record: record, Err: err}
+}
+
+// toEntry is the Converter of the adapter pairing a record with the key of the key strategy
+func (a *RecordsAdapter) toEntry(record string) (Entry, error) {
+	return Entry{Key: a.keys(record), Value: record}, nil
+}
+
+// rollback will run the undo functions in reverse order and return the cause
+// wrapped with the first error of the rollback if it did not complete
+func rollback(undo []func() error, cause error) error {
+	for i := len(undo) - 1; i >= 0; i-- {
+		if err := undo[i](); err != nil {
+			return errors.Wrapf(cause, "rollback failed: %v", err)
+		}
+	}
+	return cause
+}
+
+// addEntry is the Sink of the adapter adding the entry to the modern API
+func (a *RecordsAdapter) addEntry(entry Entry) error {
+	return a.modern.AddEntry(entry.Key, entry.Value)
+}
@@ -1,6 +1,7 @@
 package adapter_test
 
 import (
+	"sort"
 	"testing"
 
 	"github.com/lkendrickd/patterns/internal/patterns/adapter"
@@ -53,6 +54,32 @@ func TestEntriesAPI(t *testing.T) {
 	}
 }
 
+func TestEntriesAPIRemoveEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		wantErr  bool
+		expected map[string]string
+	}{
+		{"RemoveExistingEntry", "key1", false, map[string]string{}},
+		{"RemoveMissingEntry", "key2", true, map[string]string{"key1": "value1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := adapter.NewEntriesAPI()
+			api.AddEntry("key1", "value1")
+
+			if err := api.RemoveEntry(tt.key); (err != nil) != tt.wantErr {
+				t.Errorf("RemoveEntry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !equalMap(api.Entries(), tt.expected) {
+				t.Errorf("Entries() = %v, want %v", api.Entries(), tt.expected)
+			}
+		})
+	}
+}
+
 func TestAdapter(t *testing.T) {
 	legacy := adapter.NewRecordsAPI()
 	modern := adapter.NewEntriesAPI()
@@ -130,3 +157,13 @@ func equalMap(a, b map[string]string) bool {
 	}
 	return true
 }
+
+// sortedValues returns the values of the map in sorted order
+func sortedValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,241 @@
+package adapter
+
+// The audit log is the record of a migration. Every legacy record the adapter touches is
+// reported to the audit sink with the key it got, when it happened, the transformation it
+// went through and the outcome, so after a conversion it can be shown which legacy record
+// became which modern entry and why the others did not.
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcome is what happened to a legacy record
+type Outcome string
+
+const (
+	// OutcomeConverted is a record written to the modern API
+	OutcomeConverted Outcome = "converted"
+	// OutcomeFailed is a record the conversion or the modern API rejected
+	OutcomeFailed Outcome = "failed"
+	// OutcomeDropped is a record a pipeline stage dropped
+	OutcomeDropped Outcome = "dropped"
+	// OutcomeRolledBack is a converted record removed again by a Transactional rollback
+	OutcomeRolledBack Outcome = "rolled-back"
+)
+
+// AuditEvent is the audit record of one legacy record
+type AuditEvent struct {
+	Index     int       `json:"index"`           // Index is the position of the record in the legacy API
+	Record    string    `json:"record"`          // Record is the legacy record
+	Key       string    `json:"key,omitempty"`   // Key is the key of the modern entry
+	Value     string    `json:"value,omitempty"` // Value is the value after the transformation
+	Time      time.Time `json:"time"`
+	Transform string    `json:"transform"` // Transform is the pipeline stages applied or none
+	Outcome   Outcome   `json:"outcome"`
+	Reason    string    `json:"reason,omitempty"` // Reason is why the record failed or was dropped
+}
+
+// AuditSink receives the audit events of the adapter, it must be safe for concurrent use
+// as ConvertParallel reports from every worker
+type AuditSink interface {
+	Record(event AuditEvent)
+}
+
+// MemoryAudit is the AuditSink keeping the events in memory
+type MemoryAudit struct {
+	mu     sync.Mutex
+	events []AuditEvent
+}
+
+// NewMemoryAudit will return a new MemoryAudit struct
+func NewMemoryAudit() *MemoryAudit {
+	return &MemoryAudit{}
+}
+
+// Record will keep the event and implements the AuditSink interface
+func (m *MemoryAudit) Record(event AuditEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, event)
+}
+
+// Events will return a copy of the events in the order they were recorded
+func (m *MemoryAudit) Events() []AuditEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.events)
+}
+
+// Report will return the migration report of the events
+func (m *MemoryAudit) Report() MigrationReport {
+	return NewMigrationReport(m.Events())
+}
+
+// MultiAudit will return the AuditSink recording every event to each of the sinks in order
+func MultiAudit(sinks ...AuditSink) AuditSink {
+	return multiAudit(sinks)
+}
+
+// multiAudit is the AuditSink of MultiAudit
+type multiAudit []AuditSink
+
+// Record will record the event to every sink and implements the AuditSink interface
+func (m multiAudit) Record(event AuditEvent) {
+	for _, sink := range m {
+		sink.Record(event)
+	}
+}
+
+// JSONLinesAudit is the AuditSink writing every event as a line of JSON
+type JSONLinesAudit struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	err     error
+}
+
+// NewJSONLinesAudit will return a new JSONLinesAudit writing to w
+func NewJSONLinesAudit(w io.Writer) *JSONLinesAudit {
+	return &JSONLinesAudit{encoder: json.NewEncoder(w)}
+}
+
+// Record will write the event and implements the AuditSink interface. The interface has no
+// room for an error so the first failed write is kept for Err and the rest are skipped.
+func (j *JSONLinesAudit) Record(event AuditEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.err == nil {
+		j.err = j.encoder.Encode(event)
+	}
+}
+
+// Err will return the error of the first event that could not be written
+func (j *JSONLinesAudit) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// MigrationReport summarizes the audit events of a migration
+type MigrationReport struct {
+	Converted  int
+	Failed     int
+	Dropped    int
+	RolledBack int
+	Failures   []AuditEvent // Failures are the failed and dropped events in order
+}
+
+// NewMigrationReport will return the report of the audit events, a rolled back record
+// is no longer counted as converted
+func NewMigrationReport(events []AuditEvent) MigrationReport {
+	var r MigrationReport
+	for _, event := range events {
+		switch event.Outcome {
+		case OutcomeConverted:
+			r.Converted++
+		case OutcomeRolledBack:
+			r.Converted--
+			r.RolledBack++
+		case OutcomeFailed:
+			r.Failed++
+			r.Failures = append(r.Failures, event)
+		case OutcomeDropped:
+			r.Dropped++
+			r.Failures = append(r.Failures, event)
+		}
+	}
+	return r
+}
+
+// String will render the counts followed by every failed or dropped record
+func (r MigrationReport) String() string {
+	var b strings.Builder
+
+	b.WriteString("migration report\n")
+	fmt.Fprintf(&b, "  converted:   %d\n", r.Converted)
+	fmt.Fprintf(&b, "  failed:      %d\n", r.Failed)
+	fmt.Fprintf(&b, "  dropped:     %d\n", r.Dropped)
+	fmt.Fprintf(&b, "  rolled back: %d\n", r.RolledBack)
+
+	if len(r.Failures) > 0 {
+		b.WriteString("failures:\n")
+	}
+	for _, event := range r.Failures {
+		fmt.Fprintf(&b, "  record %d %q %s", event.Index, event.Record, event.Outcome)
+		if event.Outcome == OutcomeDropped {
+			fmt.Fprintf(&b, " by %s", event.Transform)
+		}
+		fmt.Fprintf(&b, ": %s\n", event.Reason)
+	}
+
+	return b.String()
+}
+
+/*##################################################################################
+# Helper Functions
+##################################################################################*/
+
+// audit will report the outcome of the entry converted at the index to the audit sink.
+// With a pipeline the index is traced back to the legacy record the entry came from.
+func (a *RecordsAdapter) audit(index int, entry Entry, outcome Outcome, err error) {
+	if a.auditSink == nil {
+		return
+	}
+
+	event := AuditEvent{
+		Index:     index,
+		Record:    entry.Value,
+		Key:       entry.Key,
+		Value:     entry.Value,
+		Time:      time.Now().UTC(),
+		Transform: "none",
+		Outcome:   outcome,
+	}
+	if r := a.pipelineResult; r != nil && index < len(r.Items) {
+		event.Index, event.Record = a.origin(index, entry.Value)
+		event.Transform = strings.Join(r.Stages, " -> ")
+	}
+	if err != nil {
+		event.Reason = err.Error()
+	}
+
+	a.auditSink.Record(event)
+}
+
+// auditPipeline will report the records the pipeline dropped or failed on to the audit sink
+func (a *RecordsAdapter) auditPipeline(r *PipelineResult, err error) {
+	if a.auditSink == nil {
+		return
+	}
+
+	for _, drop := range r.Dropped {
+		a.auditSink.Record(AuditEvent{
+			Index:     drop.Item.Origins[0],
+			Record:    r.Records[drop.Item.Origins[0]],
+			Value:     drop.Item.Value,
+			Time:      time.Now().UTC(),
+			Transform: drop.Stage,
+			Outcome:   OutcomeDropped,
+			Reason:    drop.Reason,
+		})
+	}
+
+	var recordErr *RecordError
+	if err != nil && errors.As(err, &recordErr) {
+		a.auditSink.Record(AuditEvent{
+			Index:     recordErr.Index,
+			Record:    r.Records[recordErr.Index],
+			Value:     recordErr.Record,
+			Time:      time.Now().UTC(),
+			Transform: strings.Join(r.Stages, " -> "),
+			Outcome:   OutcomeFailed,
+			Reason:    recordErr.Err.Error(),
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package adapter_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/patterns/adapter"
+)
+
+// outcomes will return the legacy record and outcome of every event
+func outcomes(events []adapter.AuditEvent) []string {
+	got := make([]string, len(events))
+	for i, event := range events {
+		got[i] = strings.Join([]string{event.Record, string(event.Outcome)}, ":")
+	}
+	return got
+}
+
+func TestAuditConvertRecords(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          adapter.ErrorMode
+		pipeline      adapter.Pipeline
+		records       []string
+		wantOutcomes  []string
+		wantTransform string
+		wantReport    adapter.MigrationReport
+	}{
+		{
+			name:         "EveryRecordConverted",
+			records:      []string{"foo", "bar"},
+			wantOutcomes: []string{"foo:converted", "bar:converted"},
+			wantReport:   adapter.MigrationReport{Converted: 2},
+		},
+		{
+			name:         "SkipAndCollectFailure",
+			mode:         adapter.SkipAndCollect,
+			records:      []string{"foo", "", "bar"},
+			wantOutcomes: []string{"foo:converted", ":failed", "bar:converted"},
+			wantReport:   adapter.MigrationReport{Converted: 2, Failed: 1},
+		},
+		{
+			name:         "TransactionalRollback",
+			mode:         adapter.Transactional,
+			records:      []string{"foo", "bar", ""},
+			wantOutcomes: []string{"foo:converted", "bar:converted", ":failed", "bar:rolled-back", "foo:rolled-back"},
+			wantReport:   adapter.MigrationReport{Failed: 1, RolledBack: 2},
+		},
+		{
+			name:          "PipelineTracesLegacyRecords",
+			pipeline:      adapter.NewPipeline(adapter.TrimSpace(), dropEmpty),
+			records:       []string{" foo ", " ", "bar"},
+			wantOutcomes:  []string{" :dropped", " foo :converted", "bar:converted"},
+			wantTransform: "trim -> drop-empty",
+			wantReport:    adapter.MigrationReport{Converted: 2, Dropped: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := adapter.NewMemoryAudit()
+
+			adap := adapter.NewAdapter(&stubLegacy{tt.records}, adapter.NewEntriesAPI(),
+				adapter.WithErrorMode(tt.mode),
+				adapter.WithPipeline(tt.pipeline),
+				adapter.WithAuditSink(audit),
+			)
+			adap.ConvertRecords()
+
+			events := audit.Events()
+			if got := outcomes(events); !equalSlice(got, tt.wantOutcomes) {
+				t.Errorf("audit outcomes = %q, want %q", got, tt.wantOutcomes)
+			}
+			for _, event := range events {
+				if event.Time.IsZero() {
+					t.Errorf("event %+v has no timestamp", event)
+				}
+				if event.Outcome == adapter.OutcomeConverted && event.Key == "" {
+					t.Errorf("converted event %+v has no key", event)
+				}
+				if tt.wantTransform != "" && event.Outcome == adapter.OutcomeConverted && event.Transform != tt.wantTransform {
+					t.Errorf("event transform = %q, want %q", event.Transform, tt.wantTransform)
+				}
+			}
+
+			report := audit.Report()
+			if report.Converted != tt.wantReport.Converted || report.Failed != tt.wantReport.Failed ||
+				report.Dropped != tt.wantReport.Dropped || report.RolledBack != tt.wantReport.RolledBack {
+				t.Errorf("Report() = %+v, want %+v", report, tt.wantReport)
+			}
+		})
+	}
+}
+
+func TestAuditPipelineTracesOrigins(t *testing.T) {
+	audit := adapter.NewMemoryAudit()
+
+	adap := adapter.NewAdapter(&stubLegacy{[]string{"a,b", "c"}}, adapter.NewEntriesAPI(),
+		adapter.WithPipeline(adapter.NewPipeline(adapter.Split("split", func(v string) []string { return strings.Split(v, ",") }))),
+		adapter.WithAuditSink(audit),
+	)
+	if err := adap.ConvertRecords(); err != nil {
+		t.Fatalf("ConvertRecords() error = %v", err)
+	}
+
+	var got []string
+	for _, event := range audit.Events() {
+		got = append(got, event.Record+"->"+event.Value)
+	}
+	if want := []string{"a,b->a", "a,b->b", "c->c"}; !equalSlice(got, want) {
+		t.Errorf("audit events = %q, want %q", got, want)
+	}
+}
+
+func TestAuditStreamAndParallel(t *testing.T) {
+	records := []string{"foo", "", "bar", "baz"}
+
+	convert := map[string]func(a *adapter.RecordsAdapter) error{
+		"ConvertStream": func(a *adapter.RecordsAdapter) error {
+			_, err := a.ConvertStream(2, nil)
+			return err
+		},
+		"ConvertParallel": func(a *adapter.RecordsAdapter) error {
+			return a.ConvertParallel(adapter.ParallelOptions{Workers: 3})
+		},
+	}
+
+	for name, fn := range convert {
+		t.Run(name, func(t *testing.T) {
+			audit := adapter.NewMemoryAudit()
+			adap := adapter.NewAdapter(&stubLegacy{records}, adapter.NewEntriesAPI(),
+				adapter.WithErrorMode(adapter.SkipAndCollect),
+				adapter.WithAuditSink(audit),
+			)
+			fn(adap)
+
+			report := audit.Report()
+			if report.Converted != 3 || report.Failed != 1 || report.Failures[0].Index != 1 {
+				t.Errorf("Report() = %+v, want 3 converted and record 1 failed", report)
+			}
+		})
+	}
+}
+
+func TestDryRunIsNotAudited(t *testing.T) {
+	audit := adapter.NewMemoryAudit()
+	adap := adapter.NewAdapter(&stubLegacy{[]string{"foo", " "}}, adapter.NewEntriesAPI(),
+		adapter.WithPipeline(adapter.NewPipeline(adapter.TrimSpace(), dropEmpty)),
+		adapter.WithAuditSink(audit),
+	)
+
+	if _, err := adap.DryRun(); err != nil {
+		t.Fatalf("DryRun() error = %v", err)
+	}
+	if events := audit.Events(); len(events) != 0 {
+		t.Errorf("DryRun() audited %v", events)
+	}
+}
+
+func TestJSONLinesAudit(t *testing.T) {
+	var buf bytes.Buffer
+	audit := adapter.NewJSONLinesAudit(&buf)
+
+	adap := adapter.NewAdapter(&stubLegacy{[]string{"foo", ""}}, adapter.NewEntriesAPI(),
+		adapter.WithErrorMode(adapter.SkipAndCollect),
+		adapter.WithAuditSink(audit),
+	)
+	adap.ConvertRecords()
+
+	if audit.Err() != nil {
+		t.Fatalf("Err() = %v", audit.Err())
+	}
+
+	var events []adapter.AuditEvent
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var event adapter.AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("line %q is not an audit event: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+
+	if got := outcomes(events); !equalSlice(got, []string{"foo:converted", ":failed"}) {
+		t.Errorf("audit lines = %q", got)
+	}
+	if events[1].Reason == "" || events[1].Transform != "none" {
+		t.Errorf("failed event = %+v, want a reason and no transform", events[1])
+	}
+
+	failing := adapter.NewJSONLinesAudit(failingWriter{})
+	failing.Record(adapter.AuditEvent{})
+	if failing.Err() == nil {
+		t.Errorf("Err() = nil, want the write error")
+	}
+}
+
+func TestMigrationReportString(t *testing.T) {
+	report := adapter.NewMigrationReport([]adapter.AuditEvent{
+		{Index: 0, Record: "foo", Outcome: adapter.OutcomeConverted},
+		{Index: 1, Record: "", Outcome: adapter.OutcomeFailed, Reason: "key and value must not be empty"},
+		{Index: 2, Record: " ", Outcome: adapter.OutcomeDropped, Transform: "drop-empty", Reason: "empty record"},
+	})
+
+	want := `migration report
+  converted:   1
+  failed:      1
+  dropped:     1
+  rolled back: 0
+failures:
+  record 1 "" failed: key and value must not be empty
+  record 2 " " dropped by drop-empty: empty record
+`
+	if got := report.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// failingWriter is a writer failing every write
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestMultiAudit(t *testing.T) {
+	first, second := adapter.NewMemoryAudit(), adapter.NewMemoryAudit()
+
+	adapter.MultiAudit(first, second).Record(adapter.AuditEvent{Record: "foo", Outcome: adapter.OutcomeConverted})
+
+	if len(first.Events()) != 1 || len(second.Events()) != 1 {
+		t.Errorf("MultiAudit() recorded %d and %d events, want 1 each", len(first.Events()), len(second.Events()))
+	}
+}
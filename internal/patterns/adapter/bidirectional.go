@@ -0,0 +1,334 @@
+package adapter
+
+// The bidirectional adapter keeps the legacy and modern APIs in step while both are in use.
+// Every converted record is linked to its modern key together with the value both sides
+// agreed on at the last reconcile. The legacy records have no keys so every run finds the
+// record of each link again by that value, a record removed from or inserted into the
+// legacy API does not move the links of the other records. Comparing each side with that base value tells which
+// side changed: a change on one side is copied to the other, a change on both sides to
+// different values is a conflict that is reported and left for the caller to resolve.
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// WritableLegacyAPI is the legacy API interface that also accepts writes
+type WritableLegacyAPI interface {
+	LegacyAPI
+	SetRecord(index int, record string) error
+	AppendRecord(record string) error
+}
+
+// Conflict is a record that changed on both sides since the last reconcile
+type Conflict struct {
+	Key    string // Key is the key of the entry in the modern API
+	Index  int    // Index is the index of the record in the legacy API
+	Base   string // Base is the value both sides agreed on at the last reconcile
+	Legacy string // Legacy is the current value of the legacy record
+	Modern string // Modern is the current value of the modern entry
+}
+
+// String will return a description of the conflict
+func (c Conflict) String() string {
+	return fmt.Sprintf("conflict on %s (record %d): base %q, legacy %q, modern %q",
+		c.Key, c.Index, c.Base, c.Legacy, c.Modern)
+}
+
+// link ties a legacy record to a modern entry and holds the value both agreed on
+type link struct {
+	index int // index is the position of the record found by the last run, -1 when it is gone
+	base  string
+}
+
+// BidirectionalAdapter is the struct that adapts the records in both directions
+type BidirectionalAdapter struct {
+	legacy WritableLegacyAPI
+	modern ModernAPI
+	keys   KeyStrategy
+	links  map[string]*link // links are the linked records by modern key
+}
+
+// NewBidirectionalAdapter will return a new BidirectionalAdapter struct configured by the
+// option WithKeyStrategy
+func NewBidirectionalAdapter(legacy WritableLegacyAPI, modern ModernAPI, opts ...BidirectionalOption) *BidirectionalAdapter {
+	o := newOptions(opts, BidirectionalOption.applyBidirectional)
+	return &BidirectionalAdapter{
+		legacy: legacy,
+		modern: modern,
+		keys:   o.keys,
+		links:  make(map[string]*link),
+	}
+}
+
+// Forward will copy the legacy side to the modern side. New legacy records are added and
+// linked, records changed only on the legacy side are written to their modern entry.
+// A legacy side that can not be read is an error and nothing is changed.
+// A new record is linked to an unlinked modern entry that already holds its value, the
+// entry of its key first. Otherwise it gets a key of its own, a record whose key is already
+// linked or in use in the modern API such as a duplicate with ContentHashKeys gets the key
+// suffixed with its legacy index so it never overwrites another entry.
+func (b *BidirectionalAdapter) Forward() ([]Conflict, error) {
+	entries := b.modern.Entries()
+	records, err := b.records()
+	if err != nil {
+		return nil, err
+	}
+	b.relink(records)
+
+	linked := make(map[int]bool)
+	for _, l := range b.links {
+		linked[l.index] = true
+	}
+
+	for index, record := range records {
+		if linked[index] {
+			continue
+		}
+
+		key, ok := b.existingKey(b.keys(record), record, entries)
+		if !ok {
+			key = b.uniqueKey(b.keys(record), index, entries)
+			entries[key] = record
+			if err := b.modern.AddEntry(key, record); err != nil {
+				return nil, err
+			}
+		}
+		b.links[key] = &link{index: index, base: record}
+	}
+
+	return b.reconcile(func(c Conflict) error {
+		// only the legacy side changed so the modern entry follows it
+		if c.Legacy != c.Base && c.Modern == c.Base {
+			return b.modern.AddEntry(c.Key, c.Legacy)
+		}
+		return nil
+	})
+}
+
+// WriteBack will project the modern side back into the legacy records. New modern entries
+// are appended to the legacy records, entries changed only on the modern side overwrite
+// their legacy record. Entries removed from the modern API are left untouched.
+func (b *BidirectionalAdapter) WriteBack() ([]Conflict, error) {
+	entries := b.modern.Entries()
+
+	// append the new entries in key order so the legacy order is stable
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		if _, ok := b.links[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	// count the records locally as reading them back after every append can be a remote call
+	records, err := b.records()
+	if err != nil {
+		return nil, err
+	}
+	b.relink(records)
+	count := len(records)
+	for _, key := range keys {
+		if err := b.legacy.AppendRecord(entries[key]); err != nil {
+			return nil, err
+		}
+		b.links[key] = &link{index: count, base: entries[key]}
+		count++
+	}
+
+	return b.reconcile(func(c Conflict) error {
+		// only the modern side changed so the legacy record follows it
+		if c.Modern != c.Base && c.Legacy == c.Base {
+			return b.legacy.SetRecord(c.Index, c.Modern)
+		}
+		return nil
+	})
+}
+
+// reconcile will compare both sides of every link with its base value. Links where both sides
+// changed to different values are returned as conflicts, the rest are handed to apply and
+// get the current value as their new base.
+func (b *BidirectionalAdapter) reconcile(apply func(c Conflict) error) ([]Conflict, error) {
+	records, err := b.records()
+	if err != nil {
+		return nil, err
+	}
+	b.relink(records)
+	entries := b.modern.Entries()
+
+	keys := make([]string, 0, len(b.links))
+	for key := range b.links {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var conflicts []Conflict
+	for _, key := range keys {
+		l := b.links[key]
+		modern, ok := entries[key]
+		if !ok || l.index < 0 || l.index >= len(records) {
+			continue
+		}
+
+		c := Conflict{Key: key, Index: l.index, Base: l.base, Legacy: records[l.index], Modern: modern}
+		switch {
+		case c.Legacy == c.Modern:
+			// both sides agree even if both changed
+			l.base = c.Legacy
+		case c.Legacy != c.Base && c.Modern != c.Base:
+			conflicts = append(conflicts, c)
+		default:
+			if err := apply(c); err != nil {
+				return conflicts, err
+			}
+		}
+	}
+
+	// the applied changes become the new base values where both sides now agree
+	if records, err = b.records(); err != nil {
+		return conflicts, err
+	}
+	entries = b.modern.Entries()
+	for key, l := range b.links {
+		if modern, ok := entries[key]; ok && l.index >= 0 && l.index < len(records) && records[l.index] == modern {
+			l.base = modern
+		}
+	}
+
+	return conflicts, nil
+}
+
+/*##################################################################################
+# Helper Functions
+##################################################################################*/
+
+// records will read the legacy records through StreamRecords when the legacy API streams
+// so a failed read such as an unreachable service is an error instead of no records
+func (b *BidirectionalAdapter) records() ([]string, error) {
+	streamer, ok := b.legacy.(RecordStreamer)
+	if !ok {
+		return b.legacy.Records(), nil
+	}
+
+	var records []string
+	err := streamer.StreamRecords(func(record string) bool {
+		records = append(records, record)
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read legacy records")
+	}
+	return records, nil
+}
+
+// relink will find the record of every link in the current legacy records. The links are
+// matched to the records holding their base value in legacy order. A link whose value is
+// gone takes the record between its matched neighbours when exactly as many records as
+// links are left there, its record changed on the legacy side, otherwise the record was
+// removed and the link keeps its modern entry with the index -1 so it is left alone.
+func (b *BidirectionalAdapter) relink(records []string) {
+	keys := make([]string, 0, len(b.links))
+	for key, l := range b.links {
+		if l.index >= 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if b.links[keys[i]].index != b.links[keys[j]].index {
+			return b.links[keys[i]].index < b.links[keys[j]].index
+		}
+		return keys[i] < keys[j]
+	})
+
+	positions := make(map[string][]int)
+	for index, record := range records {
+		positions[record] = append(positions[record], index)
+	}
+	claimed := make([]bool, len(records))
+	found := make([]int, len(keys))
+	for i, key := range keys {
+		found[i] = -1
+		if free := positions[b.links[key].base]; len(free) > 0 {
+			found[i] = free[0]
+			positions[b.links[key].base] = free[1:]
+			claimed[free[0]] = true
+		}
+	}
+
+	// the unmatched links between two matched ones take the unclaimed records between them
+	for start := 0; start < len(keys); start++ {
+		if found[start] >= 0 {
+			continue
+		}
+		end := start
+		for end < len(keys) && found[end] < 0 {
+			end++
+		}
+		low, high := -1, len(records)
+		if start > 0 {
+			low = found[start-1]
+		}
+		if end < len(keys) {
+			high = found[end]
+		}
+
+		var free []int
+		for index := low + 1; index < high; index++ {
+			if !claimed[index] {
+				free = append(free, index)
+			}
+		}
+		if len(free) == end-start {
+			for i, index := range free {
+				found[start+i] = index
+				claimed[index] = true
+			}
+		}
+		start = end
+	}
+
+	for i, key := range keys {
+		b.links[key].index = found[i]
+	}
+}
+
+// existingKey will return the key of an unlinked modern entry holding the record, the key
+// the strategy gave the record first and the other entries in key order after it
+func (b *BidirectionalAdapter) existingKey(key string, record string, entries map[string]string) (string, bool) {
+	if _, linked := b.links[key]; !linked && entries[key] == record {
+		return key, true
+	}
+
+	candidates := make([]string, 0, len(entries))
+	for candidate, value := range entries {
+		if _, linked := b.links[candidate]; !linked && value == record {
+			candidates = append(candidates, candidate)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	sort.Strings(candidates)
+	return candidates[0], true
+}
+
+// uniqueKey will return the key if no link or modern entry uses it yet, otherwise the key
+// suffixed with the legacy index and a counter if that is taken as well
+func (b *BidirectionalAdapter) uniqueKey(key string, index int, entries map[string]string) string {
+	taken := func(key string) bool {
+		_, linked := b.links[key]
+		_, exists := entries[key]
+		return linked || exists
+	}
+
+	unique := key
+	for n := 0; taken(unique); n++ {
+		unique = fmt.Sprintf("%s-%d", key, index)
+		if n > 0 {
+			unique = fmt.Sprintf("%s-%d-%d", key, index, n)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,306 @@
+package adapter_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/patterns/adapter"
+)
+
+func TestBidirectionalAdapter(t *testing.T) {
+	tests := []struct {
+		name          string
+		change        func(legacy *adapter.RecordsAPI, modern *adapter.EntriesAPI)
+		direction     string
+		wantRecords   []string
+		wantEntries   []string
+		wantConflicts int
+	}{
+		{
+			name:        "NoChanges",
+			change:      func(*adapter.RecordsAPI, *adapter.EntriesAPI) {},
+			direction:   "back",
+			wantRecords: []string{"foo", "bar", "baz"},
+			wantEntries: []string{"bar", "baz", "foo"},
+		},
+		{
+			name: "ModernUpdateWrittenBack",
+			change: func(_ *adapter.RecordsAPI, modern *adapter.EntriesAPI) {
+				modern.AddEntry(key("bar"), "BAR")
+			},
+			direction:   "back",
+			wantRecords: []string{"foo", "BAR", "baz"},
+			wantEntries: []string{"BAR", "baz", "foo"},
+		},
+		{
+			name: "ModernInsertAppended",
+			change: func(_ *adapter.RecordsAPI, modern *adapter.EntriesAPI) {
+				modern.AddEntry("new", "qux")
+			},
+			direction:   "back",
+			wantRecords: []string{"foo", "bar", "baz", "qux"},
+			wantEntries: []string{"bar", "baz", "foo", "qux"},
+		},
+		{
+			name: "LegacyUpdateForwarded",
+			change: func(legacy *adapter.RecordsAPI, _ *adapter.EntriesAPI) {
+				legacy.SetRecord(0, "FOO")
+			},
+			direction:   "forward",
+			wantRecords: []string{"FOO", "bar", "baz"},
+			wantEntries: []string{"FOO", "bar", "baz"},
+		},
+		{
+			name: "LegacyAppendForwarded",
+			change: func(legacy *adapter.RecordsAPI, _ *adapter.EntriesAPI) {
+				legacy.AppendRecord("qux")
+			},
+			direction:   "forward",
+			wantRecords: []string{"foo", "bar", "baz", "qux"},
+			wantEntries: []string{"bar", "baz", "foo", "qux"},
+		},
+		{
+			name: "BothChangedConflict",
+			change: func(legacy *adapter.RecordsAPI, modern *adapter.EntriesAPI) {
+				legacy.SetRecord(2, "legacy-baz")
+				modern.AddEntry(key("baz"), "modern-baz")
+			},
+			direction:     "back",
+			wantRecords:   []string{"foo", "bar", "legacy-baz"},
+			wantEntries:   []string{"bar", "foo", "modern-baz"},
+			wantConflicts: 1,
+		},
+		{
+			name: "BothChangedSameValue",
+			change: func(legacy *adapter.RecordsAPI, modern *adapter.EntriesAPI) {
+				legacy.SetRecord(2, "BAZ")
+				modern.AddEntry(key("baz"), "BAZ")
+			},
+			direction:   "forward",
+			wantRecords: []string{"foo", "bar", "BAZ"},
+			wantEntries: []string{"BAZ", "bar", "foo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			legacy := adapter.NewRecordsAPI()
+			modern := adapter.NewEntriesAPI()
+			bidi := adapter.NewBidirectionalAdapter(legacy, modern, adapter.WithKeyStrategy(adapter.ContentHashKeys()))
+
+			if _, err := bidi.Forward(); err != nil {
+				t.Fatalf("Forward() error = %v", err)
+			}
+
+			tt.change(legacy, modern)
+
+			var (
+				conflicts []adapter.Conflict
+				err       error
+			)
+			if tt.direction == "forward" {
+				conflicts, err = bidi.Forward()
+			} else {
+				conflicts, err = bidi.WriteBack()
+			}
+			if err != nil {
+				t.Fatalf("%s error = %v", tt.direction, err)
+			}
+
+			if len(conflicts) != tt.wantConflicts {
+				t.Errorf("conflicts = %v, want %d", conflicts, tt.wantConflicts)
+			}
+			if got := legacy.Records(); !equalSlice(got, tt.wantRecords) {
+				t.Errorf("Records() = %v, want %v", got, tt.wantRecords)
+			}
+			if got := sortedValues(modern.Entries()); !equalSlice(got, tt.wantEntries) {
+				t.Errorf("Entries() = %v, want %v", got, tt.wantEntries)
+			}
+		})
+	}
+}
+
+func TestBidirectionalAdapterConflictResolved(t *testing.T) {
+	legacy := adapter.NewRecordsAPI()
+	modern := adapter.NewEntriesAPI()
+	bidi := adapter.NewBidirectionalAdapter(legacy, modern, adapter.WithKeyStrategy(adapter.ContentHashKeys()))
+	bidi.Forward()
+
+	legacy.SetRecord(0, "legacy-foo")
+	modern.AddEntry(key("foo"), "modern-foo")
+	if conflicts, _ := bidi.WriteBack(); len(conflicts) != 1 {
+		t.Fatalf("WriteBack() conflicts = %v, want 1", conflicts)
+	}
+
+	// resolve the conflict in favor of the modern side then write a later change back
+	legacy.SetRecord(0, "modern-foo")
+	if conflicts, _ := bidi.WriteBack(); len(conflicts) != 0 {
+		t.Fatalf("WriteBack() conflicts after resolving = %v", conflicts)
+	}
+
+	modern.AddEntry(key("foo"), "later-foo")
+	bidi.WriteBack()
+	if got := legacy.Records()[0]; got != "later-foo" {
+		t.Errorf("Records()[0] = %q, want later-foo", got)
+	}
+}
+
+// key returns the content hash key of a record
+func key(record string) string {
+	return adapter.ContentHashKeys()(record)
+}
+
+// TestBidirectionalAdapterDuplicateRecords checks duplicate records sharing a content hash
+// key are linked to keys of their own so a modern edit is not reverted by the next Forward
+func TestBidirectionalAdapterDuplicateRecords(t *testing.T) {
+	legacy := adapter.NewRecordsAPI(adapter.WithRecords("foo", "foo", "bar"))
+	modern := adapter.NewEntriesAPI()
+	bidi := adapter.NewBidirectionalAdapter(legacy, modern, adapter.WithKeyStrategy(adapter.ContentHashKeys()))
+
+	if _, err := bidi.Forward(); err != nil {
+		t.Fatalf("Forward() error = %v", err)
+	}
+	if modern.Len() != 3 {
+		t.Fatalf("Entries() = %v, want an entry per record", modern.Entries())
+	}
+
+	modern.AddEntry(key("foo"), "FOO")
+	conflicts, err := bidi.Forward()
+	if err != nil || len(conflicts) != 0 {
+		t.Fatalf("Forward() = %v, %v", conflicts, err)
+	}
+	if got := sortedValues(modern.Entries()); !equalSlice(got, []string{"FOO", "bar", "foo"}) {
+		t.Errorf("Entries() = %v, want the modern edit kept", got)
+	}
+
+	if _, err := bidi.WriteBack(); err != nil {
+		t.Fatalf("WriteBack() error = %v", err)
+	}
+	if got := legacy.Records(); !equalSlice(got, []string{"FOO", "foo", "bar"}) {
+		t.Errorf("Records() = %v, want only the first record changed", got)
+	}
+}
+
+// countingLegacy counts the reads of the legacy records
+type countingLegacy struct {
+	*adapter.RecordsAPI
+	reads int
+}
+
+func (c *countingLegacy) Records() []string {
+	c.reads++
+	return c.RecordsAPI.Records()
+}
+
+// TestBidirectionalAdapterWriteBackAppends checks appended entries are linked to the right
+// legacy index without reading the legacy records back after every append
+func TestBidirectionalAdapterWriteBackAppends(t *testing.T) {
+	legacy := &countingLegacy{RecordsAPI: adapter.NewRecordsAPI()}
+	modern := adapter.NewEntriesAPI()
+	bidi := adapter.NewBidirectionalAdapter(legacy, modern, adapter.WithKeyStrategy(adapter.ContentHashKeys()))
+	bidi.Forward()
+
+	for i, value := range []string{"a", "b", "c", "d"} {
+		modern.AddEntry(string(rune('w'+i)), value)
+	}
+	legacy.reads = 0
+	if _, err := bidi.WriteBack(); err != nil {
+		t.Fatalf("WriteBack() error = %v", err)
+	}
+	if legacy.reads > 3 {
+		t.Errorf("WriteBack() read the legacy records %d times for 4 appends", legacy.reads)
+	}
+
+	// the last appended entry is linked to the last legacy record
+	modern.AddEntry("z", "D")
+	bidi.WriteBack()
+	if got := legacy.Records(); !equalSlice(got, []string{"foo", "bar", "baz", "a", "b", "c", "D"}) {
+		t.Errorf("Records() = %v", got)
+	}
+}
+
+// editableLegacy is a writable legacy API whose records can also be removed
+type editableLegacy struct {
+	records []string
+}
+
+func (e *editableLegacy) Records() []string {
+	return slices.Clone(e.records)
+}
+
+func (e *editableLegacy) SetRecord(index int, record string) error {
+	e.records[index] = record
+	return nil
+}
+
+func (e *editableLegacy) AppendRecord(record string) error {
+	e.records = append(e.records, record)
+	return nil
+}
+
+func (e *editableLegacy) remove(index int) {
+	e.records = slices.Delete(e.records, index, index+1)
+}
+
+func TestBidirectionalAdapterRecordRemoved(t *testing.T) {
+	legacy := &editableLegacy{records: []string{"foo", "bar", "baz"}}
+	modern := adapter.NewEntriesAPI()
+	bidi := adapter.NewBidirectionalAdapter(legacy, modern, adapter.WithKeyStrategy(adapter.ContentHashKeys()))
+
+	if _, err := bidi.Forward(); err != nil {
+		t.Fatalf("Forward() error = %v", err)
+	}
+
+	// baz moves to the index of bar which must not follow it
+	legacy.remove(1)
+	if conflicts, err := bidi.Forward(); err != nil || len(conflicts) != 0 {
+		t.Fatalf("Forward() = %v, %v", conflicts, err)
+	}
+	want := map[string]string{key("foo"): "foo", key("bar"): "bar", key("baz"): "baz"}
+	if !equalMap(modern.Entries(), want) {
+		t.Fatalf("Entries() after the removal = %v, want %v", modern.Entries(), want)
+	}
+
+	// a legacy edit of the moved record reaches its own entry
+	legacy.SetRecord(1, "baz2")
+	if conflicts, err := bidi.Forward(); err != nil || len(conflicts) != 0 {
+		t.Fatalf("Forward() = %v, %v", conflicts, err)
+	}
+	want[key("baz")] = "baz2"
+	if !equalMap(modern.Entries(), want) {
+		t.Errorf("Entries() after the edit = %v, want %v", modern.Entries(), want)
+	}
+
+	// the entry of the removed record is not written back as a new record
+	modern.AddEntry(key("foo"), "FOO")
+	if _, err := bidi.WriteBack(); err != nil {
+		t.Fatalf("WriteBack() error = %v", err)
+	}
+	if got := legacy.Records(); !equalSlice(got, []string{"FOO", "baz2"}) {
+		t.Errorf("Records() = %v, want [FOO baz2]", got)
+	}
+}
+
+func TestBidirectionalAdapterLinksExistingEntry(t *testing.T) {
+	legacy := adapter.NewRecordsAPI(adapter.WithRecords("foo", "bar"))
+	modern := adapter.NewEntriesAPI()
+	modern.AddEntry(key("foo"), "foo")
+	modern.AddEntry("migrated-bar", "bar")
+	bidi := adapter.NewBidirectionalAdapter(legacy, modern, adapter.WithKeyStrategy(adapter.ContentHashKeys()))
+
+	if _, err := bidi.Forward(); err != nil {
+		t.Fatalf("Forward() error = %v", err)
+	}
+	if modern.Len() != 2 {
+		t.Fatalf("Entries() = %v, want the existing entries linked", modern.Entries())
+	}
+
+	// the linked entry follows the legacy record
+	legacy.SetRecord(1, "bar2")
+	if _, err := bidi.Forward(); err != nil {
+		t.Fatalf("Forward() error = %v", err)
+	}
+	if value, _ := modern.Get("migrated-bar"); value != "bar2" || modern.Len() != 2 {
+		t.Errorf("Entries() = %v, want migrated-bar linked to the record", modern.Entries())
+	}
+}
@@ -0,0 +1,113 @@
+package adapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ChangeKind is how an entry would change
+type ChangeKind string
+
+const (
+	// ChangeAdded is an entry the modern API does not have yet
+	ChangeAdded ChangeKind = "added"
+	// ChangeChanged is an entry whose value would be overwritten
+	ChangeChanged ChangeKind = "changed"
+	// ChangeUnchanged is an entry that already has the value
+	ChangeUnchanged ChangeKind = "unchanged"
+	// ChangeRejected is an entry the modern API would reject
+	ChangeRejected ChangeKind = "rejected"
+)
+
+// Change is a single entry ConvertRecords would write
+type Change struct {
+	Key  string     `json:"key"`
+	Kind ChangeKind `json:"kind"`
+	Old  string     `json:"old,omitempty"` // Old is the current value of a changed entry
+	New  string     `json:"new"`
+	// Error is why the modern API would reject the entry of a rejected change
+	Error string `json:"error,omitempty"`
+}
+
+// Diff is the difference between the current entries and the result of ConvertRecords
+type Diff struct {
+	Changes   []Change `json:"changes"`
+	Added     int      `json:"added"`
+	Changed   int      `json:"changed"`
+	Unchanged int      `json:"unchanged"`
+	Rejected  int      `json:"rejected"`
+}
+
+// DryRun will compute what ConvertRecords would write without writing anything. The
+// changes are listed in the order of the records after the pipeline. A stateful key
+// strategy such as SequentialKeys still advances so the keys of the following conversion
+// differ from the dry run, use ContentHashKeys for a preview that matches. The entries are
+// checked like the modern API checks them on a write and the ones it would reject are
+// listed as rejected whatever the error mode, where ConvertRecords fails fast by default.
+func (a *RecordsAdapter) DryRun() (*Diff, error) {
+	// apply the changes to a copy so a key written twice compares with its first write
+	entries := copyMap(a.modern.Entries())
+	diff := &Diff{Changes: []Change{}}
+
+	// nothing is converted so nothing is audited
+	preview := *a
+	preview.auditSink = nil
+
+	_, err := New(preview.records, a.toEntry, func(entry Entry) error {
+		change := Change{Key: entry.Key, New: entry.Value}
+
+		if err := checkEntry(a.modern, entry.Key, entry.Value); err != nil {
+			change.Kind = ChangeRejected
+			change.Error = err.Error()
+			diff.Rejected++
+			diff.Changes = append(diff.Changes, change)
+			return nil
+		}
+
+		old, ok := entries[entry.Key]
+		switch {
+		case !ok:
+			change.Kind = ChangeAdded
+			diff.Added++
+		case old != entry.Value:
+			change.Kind = ChangeChanged
+			change.Old = old
+			diff.Changed++
+		default:
+			change.Kind = ChangeUnchanged
+			diff.Unchanged++
+		}
+
+		entries[entry.Key] = entry.Value
+		diff.Changes = append(diff.Changes, change)
+		return nil
+	}).Convert()
+
+	return diff, err
+}
+
+// Text will render the diff with a line per change and a summary line
+// added entries are marked with +, changed ones with ~ and rejected ones with !
+func (d *Diff) Text() string {
+	var b strings.Builder
+	for _, change := range d.Changes {
+		switch change.Kind {
+		case ChangeAdded:
+			fmt.Fprintf(&b, "+ %s: %s\n", change.Key, change.New)
+		case ChangeChanged:
+			fmt.Fprintf(&b, "~ %s: %s -> %s\n", change.Key, change.Old, change.New)
+		case ChangeRejected:
+			fmt.Fprintf(&b, "! %s: %s rejected: %s\n", change.Key, change.New, change.Error)
+		default:
+			fmt.Fprintf(&b, "  %s: %s\n", change.Key, change.New)
+		}
+	}
+	fmt.Fprintf(&b, "%d added, %d changed, %d unchanged, %d rejected\n", d.Added, d.Changed, d.Unchanged, d.Rejected)
+	return b.String()
+}
+
+// JSON will render the diff as indented JSON
+func (d *Diff) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
@@ -0,0 +1,148 @@
+package adapter_test
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/patterns/adapter"
+)
+
+func TestDryRun(t *testing.T) {
+	modern := adapter.NewEntriesAPI()
+	modern.AddEntry(key("foo"), "foo")
+	modern.AddEntry(key("bar"), "old-bar")
+	modern.AddEntry("other", "kept")
+
+	// the records are keyed by content so bar is keyed like the existing old-bar entry
+	adap := adapter.NewAdapter(adapter.NewRecordsAPI(), modern, adapter.WithKeyStrategy(adapter.ContentHashKeys()))
+
+	diff, err := adap.DryRun()
+	if err != nil {
+		t.Fatalf("DryRun() error = %v", err)
+	}
+
+	want := []adapter.Change{
+		{Key: key("foo"), Kind: adapter.ChangeUnchanged, New: "foo"},
+		{Key: key("bar"), Kind: adapter.ChangeChanged, Old: "old-bar", New: "bar"},
+		{Key: key("baz"), Kind: adapter.ChangeAdded, New: "baz"},
+	}
+	if len(diff.Changes) != len(want) {
+		t.Fatalf("Changes = %v, want %v", diff.Changes, want)
+	}
+	for i := range want {
+		if diff.Changes[i] != want[i] {
+			t.Errorf("Changes[%d] = %+v, want %+v", i, diff.Changes[i], want[i])
+		}
+	}
+	if diff.Added != 1 || diff.Changed != 1 || diff.Unchanged != 1 {
+		t.Errorf("counts = %d/%d/%d, want 1/1/1", diff.Added, diff.Changed, diff.Unchanged)
+	}
+
+	// the dry run does not touch the target
+	if !equalMap(modern.Entries(), map[string]string{key("foo"): "foo", key("bar"): "old-bar", "other": "kept"}) {
+		t.Errorf("DryRun() mutated the modern API: %v", modern.Entries())
+	}
+}
+
+func TestDryRunDuplicateKey(t *testing.T) {
+	adap := adapter.NewAdapter(&stubLegacy{records: []string{"foo", "foo"}}, adapter.NewEntriesAPI(), adapter.WithKeyStrategy(adapter.ContentHashKeys()))
+
+	diff, err := adap.DryRun()
+	if err != nil {
+		t.Fatalf("DryRun() error = %v", err)
+	}
+	if diff.Added != 1 || diff.Unchanged != 1 {
+		t.Errorf("DryRun() = %+v, want the second foo unchanged", diff)
+	}
+}
+
+func TestDiffRender(t *testing.T) {
+	diff := &adapter.Diff{
+		Changes: []adapter.Change{
+			{Key: "k1", Kind: adapter.ChangeAdded, New: "foo"},
+			{Key: "k2", Kind: adapter.ChangeChanged, Old: "old", New: "bar"},
+			{Key: "k3", Kind: adapter.ChangeUnchanged, New: "baz"},
+			{Key: "k4", Kind: adapter.ChangeRejected, New: "x", Error: "too short"},
+		},
+		Added: 1, Changed: 1, Unchanged: 1, Rejected: 1,
+	}
+
+	wantText := "+ k1: foo\n~ k2: old -> bar\n  k3: baz\n! k4: x rejected: too short\n1 added, 1 changed, 1 unchanged, 1 rejected\n"
+	if got := diff.Text(); got != wantText {
+		t.Errorf("Text() = %q, want %q", got, wantText)
+	}
+
+	data, err := diff.JSON()
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	var decoded adapter.Diff
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("JSON() is not valid JSON: %v", err)
+	}
+	if len(decoded.Changes) != 4 || decoded.Changes[1].Old != "old" || decoded.Added != 1 ||
+		decoded.Changes[3].Error != "too short" || decoded.Rejected != 1 {
+		t.Errorf("JSON() decoded = %+v", decoded)
+	}
+}
+
+func TestDryRunRejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		records   []string
+		validator adapter.Validator
+		want      []adapter.ChangeKind
+	}{
+		{
+			name:    "EmptyRecord",
+			records: []string{"foo", ""},
+			want:    []adapter.ChangeKind{adapter.ChangeAdded, adapter.ChangeRejected},
+		},
+		{
+			name:    "FailingValidator",
+			records: []string{"foo", "toolong"},
+			validator: func(key, value string) error {
+				if len(value) > 3 {
+					return errors.New("value too long")
+				}
+				return nil
+			},
+			want: []adapter.ChangeKind{adapter.ChangeAdded, adapter.ChangeRejected},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newAdapter := func(modern *adapter.EntriesAPI) *adapter.RecordsAdapter {
+				return adapter.NewAdapter(&stubLegacy{records: tt.records}, modern,
+					adapter.WithKeyStrategy(adapter.ContentHashKeys()),
+					adapter.WithErrorMode(adapter.SkipAndCollect),
+				)
+			}
+
+			diff, err := newAdapter(adapter.NewEntriesAPI(adapter.WithValidator(tt.validator))).DryRun()
+			if err != nil {
+				t.Fatalf("DryRun() error = %v", err)
+			}
+			var got []adapter.ChangeKind
+			for _, change := range diff.Changes {
+				got = append(got, change.Kind)
+			}
+			if !slices.Equal(got, tt.want) || diff.Added != 1 || diff.Rejected != 1 || diff.Changes[1].Error == "" {
+				t.Fatalf("DryRun() = %+v, want %v", diff, tt.want)
+			}
+
+			// the real run rejects the same record
+			modern := adapter.NewEntriesAPI(adapter.WithValidator(tt.validator))
+			var failed adapter.ConversionErrors
+			if err := newAdapter(modern).ConvertRecords(); !errors.As(err, &failed) || len(failed) != diff.Rejected {
+				t.Errorf("ConvertRecords() error = %v, want %d rejected", err, diff.Rejected)
+			}
+			if modern.Len() != diff.Added {
+				t.Errorf("ConvertRecords() added %d entries, the dry run %d", modern.Len(), diff.Added)
+			}
+		})
+	}
+}
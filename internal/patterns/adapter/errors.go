@@ -0,0 +1,70 @@
+package adapter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorMode is how ConvertRecords handles records the modern API rejects
+type ErrorMode int
+
+const (
+	// FailFast stops at the first failing record leaving the records before it converted
+	FailFast ErrorMode = iota
+	// SkipAndCollect converts every record it can and returns the failing ones as ConversionErrors
+	SkipAndCollect
+	// Transactional rolls back every converted record when any record fails
+	Transactional
+)
+
+// String will return the name of the mode
+func (m ErrorMode) String() string {
+	switch m {
+	case SkipAndCollect:
+		return "skip-and-collect"
+	case Transactional:
+		return "transactional"
+	default:
+		return "fail-fast"
+	}
+}
+
+// RecordError is the error of a single legacy record that could not be converted
+type RecordError struct {
+	Index  int    // Index is the position of the record in the legacy API even after a pipeline
+	Record string // Record is the legacy record as read before any pipeline stage
+	Err    error  // Err is the reason the record failed
+}
+
+// Error will return the error message naming the record
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("record %d %q: %v", e.Index, e.Record, e.Err)
+}
+
+// Unwrap will return the reason the record failed
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// ConversionErrors is the error listing every record that failed in SkipAndCollect mode
+type ConversionErrors []*RecordError
+
+// Error will return the error message listing each failing record and its reason
+func (e ConversionErrors) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d records failed to convert", len(e))
+	for _, err := range e {
+		b.WriteString("\n\t")
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap will return the record errors so errors.Is and errors.As can inspect them
+func (e ConversionErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
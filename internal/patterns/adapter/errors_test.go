@@ -0,0 +1,135 @@
+package adapter_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/patterns/adapter"
+)
+
+// readOnlyModern is a ModernAPI that can not remove entries
+type readOnlyModern struct {
+	api *adapter.EntriesAPI
+}
+
+func (r readOnlyModern) Entries() map[string]string { return r.api.Entries() }
+
+func (r readOnlyModern) AddEntry(key, value string) error { return r.api.AddEntry(key, value) }
+
+func TestConvertRecordsErrorModes(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        adapter.ErrorMode
+		records     []string
+		existing    map[string]string
+		wantEntries []string
+		wantFailed  []int // indexes of the failing records
+		wantErr     bool
+	}{
+		{
+			name:        "FailFastStopsAtFirstFailure",
+			mode:        adapter.FailFast,
+			records:     []string{"foo", "", "baz", ""},
+			wantEntries: []string{"foo"},
+			wantFailed:  []int{1},
+			wantErr:     true,
+		},
+		{
+			name:        "SkipAndCollectListsEveryFailure",
+			mode:        adapter.SkipAndCollect,
+			records:     []string{"foo", "", "baz", ""},
+			wantEntries: []string{"baz", "foo"},
+			wantFailed:  []int{1, 3},
+			wantErr:     true,
+		},
+		{
+			name:        "TransactionalRollsBack",
+			mode:        adapter.Transactional,
+			records:     []string{"foo", "baz", ""},
+			wantEntries: []string{},
+			wantFailed:  []int{2},
+			wantErr:     true,
+		},
+		{
+			name:        "TransactionalRestoresOverwrittenEntries",
+			mode:        adapter.Transactional,
+			records:     []string{"foo", ""},
+			existing:    map[string]string{key("foo"): "old"},
+			wantEntries: []string{"old"},
+			wantFailed:  []int{1},
+			wantErr:     true,
+		},
+		{
+			name:        "TransactionalCommitsWithoutFailure",
+			mode:        adapter.Transactional,
+			records:     []string{"foo", "baz"},
+			wantEntries: []string{"baz", "foo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modern := adapter.NewEntriesAPI()
+			for k, v := range tt.existing {
+				modern.AddEntry(k, v)
+			}
+
+			adap := adapter.NewAdapter(&stubLegacy{records: tt.records}, modern,
+				adapter.WithKeyStrategy(adapter.ContentHashKeys()),
+				adapter.WithErrorMode(tt.mode),
+			)
+
+			err := adap.ConvertRecords()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConvertRecords() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got := sortedValues(modern.Entries()); !equalSlice(got, tt.wantEntries) {
+				t.Errorf("Entries() = %v, want %v", got, tt.wantEntries)
+			}
+
+			var failed []int
+			var collected adapter.ConversionErrors
+			var recordErr *adapter.RecordError
+			switch {
+			case errors.As(err, &collected):
+				for _, e := range collected {
+					failed = append(failed, e.Index)
+				}
+			case errors.As(err, &recordErr):
+				failed = append(failed, recordErr.Index)
+			}
+			if len(failed) != len(tt.wantFailed) {
+				t.Fatalf("failed records = %v, want %v", failed, tt.wantFailed)
+			}
+			for i := range failed {
+				if failed[i] != tt.wantFailed[i] {
+					t.Errorf("failed records = %v, want %v", failed, tt.wantFailed)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertRecordsTransactionalRequiresRemover(t *testing.T) {
+	modern := readOnlyModern{adapter.NewEntriesAPI()}
+	adap := adapter.NewAdapter(adapter.NewRecordsAPI(), modern, adapter.WithErrorMode(adapter.Transactional))
+
+	if err := adap.ConvertRecords(); err == nil {
+		t.Errorf("ConvertRecords() expected an error for a modern API without RemoveEntry")
+	}
+	if len(modern.Entries()) != 0 {
+		t.Errorf("Entries() = %v, want nothing written", modern.Entries())
+	}
+}
+
+func TestConversionErrorsMessage(t *testing.T) {
+	err := adapter.ConversionErrors{
+		{Index: 1, Record: "", Err: errors.New("key and value must not be empty")},
+	}
+
+	want := "1 records failed to convert\n\trecord 1 \"\": key and value must not be empty"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
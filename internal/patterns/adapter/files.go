@@ -0,0 +1,282 @@
+package adapter
+
+// The file backed legacy APIs read the records from the exports real legacy systems produce.
+// The file is streamed on every read so large exports are never loaded at once and read
+// errors reach the adapter through StreamRecords.
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Format is the file format of a file backed legacy API
+type Format string
+
+const (
+	// FormatCSV reads one column of a CSV file whose first row is the header, the field is
+	// the name of the column in the header or its zero based index
+	FormatCSV Format = "csv"
+	// FormatCSVNoHeader reads one column of a CSV file without a header, the field is the
+	// zero based column index
+	FormatCSVNoHeader Format = "csv-noheader"
+	// FormatJSONLines reads one string field of every JSON object line, the field is its name
+	FormatJSONLines Format = "jsonl"
+	// FormatFixedWidth reads a column range of every line, the field is start:end in bytes
+	FormatFixedWidth Format = "fixed"
+)
+
+// FileRecordsAPI is the struct that reads the records from a file and implements the
+// LegacyAPI and RecordStreamer interfaces
+type FileRecordsAPI struct {
+	path   string
+	format Format
+	parse  func(r io.Reader, yield func(string) bool) error
+	err    error
+}
+
+// NewFileRecordsAPI will return a new FileRecordsAPI reading the field of every record of the
+// file. An empty field selects the default: column 0, the "record" key or the whole line.
+func NewFileRecordsAPI(format Format, path string, field string) (*FileRecordsAPI, error) {
+	f := &FileRecordsAPI{
+		path:   path,
+		format: format,
+	}
+
+	switch format {
+	case FormatCSV:
+		f.parse = csvParser(defaultString(field, "0"), true)
+	case FormatCSVNoHeader:
+		if _, err := csvColumn(nil, defaultString(field, "0")); err != nil {
+			return nil, err
+		}
+		f.parse = csvParser(defaultString(field, "0"), false)
+	case FormatJSONLines:
+		f.parse = jsonLinesParser(defaultString(field, "record"))
+	case FormatFixedWidth:
+		start, end, err := parseRange(field)
+		if err != nil {
+			return nil, err
+		}
+		f.parse = fixedWidthParser(start, end)
+	default:
+		return nil, fmt.Errorf("unknown record format %q want csv, csv-noheader, jsonl or fixed", format)
+	}
+
+	// fail early when the file can not be read or the csv header has no such column
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if format == FormatCSV {
+		header, err := csv.NewReader(file).Read()
+		if err != nil && err != io.EOF {
+			return nil, errors.Wrapf(err, "could not read the header of %s", path)
+		}
+		if _, err := csvColumn(header, defaultString(field, "0")); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// ParseSource will return a new FileRecordsAPI from a source spec in the form format:path
+// such as csv:records.csv which is how the source is passed on the command line
+func ParseSource(spec string, field string) (*FileRecordsAPI, error) {
+	format, path, ok := strings.Cut(spec, ":")
+	if !ok || path == "" {
+		return nil, fmt.Errorf("source %q must be in the form format:path", spec)
+	}
+	return NewFileRecordsAPI(Format(format), path, field)
+}
+
+// StreamRecords will read the file yielding one record at a time and implements the RecordStreamer interface
+func (f *FileRecordsAPI) StreamRecords(yield func(record string) bool) error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := f.parse(file, yield); err != nil {
+		return errors.Wrapf(err, "could not read %s file %s", f.format, f.path)
+	}
+	return nil
+}
+
+// Records will read every record of the file and implements the LegacyAPI interface.
+// The interface has no room for an error so a failed read returns the records read
+// before the failure and the error is kept for Err.
+func (f *FileRecordsAPI) Records() []string {
+	var records []string
+	f.err = f.StreamRecords(func(record string) bool {
+		records = append(records, record)
+		return true
+	})
+	return records
+}
+
+// Err will return the error of the last call to Records
+func (f *FileRecordsAPI) Err() error {
+	return f.err
+}
+
+/*##################################################################################
+# Parsers
+##################################################################################*/
+
+// csvParser will return the parser yielding the column of every CSV row, with a header the
+// first row is skipped and the field may name the column instead of giving its index
+func csvParser(field string, header bool) func(r io.Reader, yield func(string) bool) error {
+	return func(r io.Reader, yield func(string) bool) error {
+		reader := csv.NewReader(r)
+		// legacy exports do not always have the same number of fields on every row
+		reader.FieldsPerRecord = -1
+
+		var names []string
+		if header {
+			fields, err := reader.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			names = fields
+		}
+		column, err := csvColumn(names, field)
+		if err != nil {
+			return err
+		}
+
+		first := 1
+		if header {
+			first = 2
+		}
+		for row := first; ; row++ {
+			fields, err := reader.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if column >= len(fields) {
+				return fmt.Errorf("row %d has no column %d", row, column)
+			}
+			if !yield(fields[column]) {
+				return nil
+			}
+		}
+	}
+}
+
+// jsonLinesParser will return the parser yielding the string field of every JSON line
+func jsonLinesParser(field string) func(r io.Reader, yield func(string) bool) error {
+	return func(r io.Reader, yield func(string) bool) error {
+		return scanLines(r, func(line int, text string) (bool, error) {
+			var object map[string]any
+			if err := json.Unmarshal([]byte(text), &object); err != nil {
+				return false, fmt.Errorf("line %d: %v", line, err)
+			}
+			value, ok := object[field].(string)
+			if !ok {
+				return false, fmt.Errorf("line %d has no string field %q", line, field)
+			}
+			return yield(value), nil
+		})
+	}
+}
+
+// fixedWidthParser will return the parser yielding the trimmed bytes start to end of every
+// line, an end of 0 reads to the end of the line
+func fixedWidthParser(start, end int) func(r io.Reader, yield func(string) bool) error {
+	return func(r io.Reader, yield func(string) bool) error {
+		return scanLines(r, func(line int, text string) (bool, error) {
+			if start > len(text) {
+				return false, fmt.Errorf("line %d is shorter than column %d", line, start)
+			}
+			stop := len(text)
+			if end > 0 && end < stop {
+				stop = end
+			}
+			return yield(strings.TrimSpace(text[start:stop])), nil
+		})
+	}
+}
+
+/*##################################################################################
+# Helper Functions
+##################################################################################*/
+
+// scanLines will call fn with every non blank line until fn returns false or an error
+func scanLines(r io.Reader, fn func(line int, text string) (bool, error)) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		more, err := fn(line, text)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// csvColumn will return the index of the column the field selects, a field naming a column
+// of the header takes precedence over a field that is a number
+func csvColumn(header []string, field string) (int, error) {
+	for i, name := range header {
+		if name == field {
+			return i, nil
+		}
+	}
+
+	column, err := strconv.Atoi(field)
+	if err != nil || column < 0 {
+		if header == nil {
+			return 0, fmt.Errorf("csv field %q must be a column index", field)
+		}
+		return 0, fmt.Errorf("csv field %q must be a column of the header %q or a column index", field, header)
+	}
+	return column, nil
+}
+
+// parseRange will parse a fixed width field in the form start:end where either side may be
+// left out, an empty field is the whole line
+func parseRange(field string) (int, int, error) {
+	if field == "" {
+		return 0, 0, nil
+	}
+
+	from, to, ok := strings.Cut(field, ":")
+	start, startErr := strconv.Atoi(defaultString(from, "0"))
+	end, endErr := strconv.Atoi(defaultString(to, "0"))
+	if !ok || startErr != nil || endErr != nil || start < 0 || end < 0 || (end > 0 && end <= start) {
+		return 0, 0, fmt.Errorf("fixed width field %q must be in the form start:end", field)
+	}
+	return start, end, nil
+}
+
+// defaultString will return the value or the fallback if the value is empty
+func defaultString(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
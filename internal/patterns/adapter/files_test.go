@@ -0,0 +1,133 @@
+package adapter_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/patterns/adapter"
+)
+
+func TestFileRecordsAPI(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  adapter.Format
+		path    string
+		field   string
+		want    []string
+		wantErr bool
+	}{
+		{"CSVDefaultColumn", adapter.FormatCSV, "testdata/records.csv", "", []string{"1", "2", "3"}, false},
+		{"CSVColumn", adapter.FormatCSV, "testdata/records.csv", "2", []string{"Berlin", "Paris, FR", "Rome"}, false},
+		{"CSVColumnByName", adapter.FormatCSV, "testdata/records.csv", "name", []string{"foo", "bar", "baz"}, false},
+		{"CSVBadColumn", adapter.FormatCSV, "testdata/records.csv", "x", nil, true},
+		{"CSVNoHeader", adapter.FormatCSVNoHeader, "testdata/records.csv", "1", []string{"name", "foo", "bar", "baz"}, false},
+		{"CSVNoHeaderByName", adapter.FormatCSVNoHeader, "testdata/records.csv", "name", nil, true},
+		{"JSONLinesDefaultField", adapter.FormatJSONLines, "testdata/records.jsonl", "", []string{"foo", "bar", "baz"}, false},
+		{"JSONLinesField", adapter.FormatJSONLines, "testdata/records.jsonl", "name", []string{"Foo", "Bar", "Baz"}, false},
+		{"FixedWidthRange", adapter.FormatFixedWidth, "testdata/records.txt", "4:14", []string{"foo", "bar", "baz"}, false},
+		{"FixedWidthOpenEnd", adapter.FormatFixedWidth, "testdata/records.txt", "14:", []string{"Berlin", "Paris", "Rome"}, false},
+		{"FixedWidthWholeLine", adapter.FormatFixedWidth, "testdata/records.txt", "", []string{"0001foo       Berlin", "0002bar       Paris", "0003baz       Rome"}, false},
+		{"FixedWidthBadRange", adapter.FormatFixedWidth, "testdata/records.txt", "8:4", nil, true},
+		{"UnknownFormat", adapter.Format("xml"), "testdata/records.csv", "", nil, true},
+		{"MissingFile", adapter.FormatCSV, "testdata/missing.csv", "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, err := adapter.NewFileRecordsAPI(tt.format, tt.path, tt.field)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewFileRecordsAPI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got := api.Records(); !equalSlice(got, tt.want) {
+				t.Errorf("Records() = %q, want %q", got, tt.want)
+			}
+			if api.Err() != nil {
+				t.Errorf("Err() = %v", api.Err())
+			}
+		})
+	}
+}
+
+func TestFileRecordsAPIReadErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		format  adapter.Format
+		content string
+		field   string
+	}{
+		{"CSVMissingColumn", adapter.FormatCSV, "a,b\nc\n", "1"},
+		{"CSVNoHeaderMissingColumn", adapter.FormatCSVNoHeader, "a,b\nc\n", "1"},
+		{"JSONLinesInvalid", adapter.FormatJSONLines, "{\"record\":\"foo\"}\nnot json\n", ""},
+		{"JSONLinesMissingField", adapter.FormatJSONLines, "{\"other\":\"foo\"}\n", ""},
+		{"FixedWidthShortLine", adapter.FormatFixedWidth, "abc\n", "5:8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			api, err := adapter.NewFileRecordsAPI(tt.format, path, tt.field)
+			if err != nil {
+				t.Fatalf("NewFileRecordsAPI() error = %v", err)
+			}
+
+			api.Records()
+			if api.Err() == nil {
+				t.Errorf("Err() = nil, want a read error")
+			}
+
+			// the adapter streams the file so the read error reaches ConvertRecords
+			if err := adapter.NewAdapter(api, adapter.NewEntriesAPI()).ConvertRecords(); err == nil {
+				t.Errorf("ConvertRecords() error = nil, want a read error")
+			}
+		})
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr bool
+	}{
+		{"CSV", "csv:testdata/records.csv", false},
+		{"CSVNoHeader", "csv-noheader:testdata/records.csv", false},
+		{"JSONLines", "jsonl:testdata/records.jsonl", false},
+		{"FixedWidth", "fixed:testdata/records.txt", false},
+		{"MissingPath", "csv:", true},
+		{"MissingFormat", "testdata/records.csv", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := adapter.ParseSource(tt.spec, ""); (err != nil) != tt.wantErr {
+				t.Errorf("ParseSource(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConvertFileRecords(t *testing.T) {
+	api, err := adapter.ParseSource("jsonl:testdata/records.jsonl", "")
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+
+	modern := adapter.NewEntriesAPI()
+	if err := adapter.NewAdapter(api, modern).ConvertRecords(); err != nil {
+		t.Fatalf("ConvertRecords() error = %v", err)
+	}
+	if got := sortedValues(modern.Entries()); !equalSlice(got, []string{"bar", "baz", "foo"}) {
+		t.Errorf("Entries() = %v", got)
+	}
+}
@@ -0,0 +1,70 @@
+package adapter
+
+// This is the generic form of the adapter pattern. The conversion machinery is the same
+// whatever the legacy and modern types are: values are pulled from a source, converted
+// one at a time and pushed into a sink. Only the three pluggable functions change so the
+// same Adapter can adapt arbitrary legacy types to modern ones.
+
+// Source is an iterator over the legacy values in the style of iter.Seq. It calls yield
+// for every value and stops early when yield returns false.
+type Source[S any] func(yield func(S) bool) error
+
+// Converter converts a legacy value to a modern value
+type Converter[S, T any] func(S) (T, error)
+
+// Sink receives the converted modern values
+type Sink[T any] func(T) error
+
+// Adapter is the generic adapter converting a Source of S into a Sink of T
+type Adapter[S, T any] struct {
+	source  Source[S]
+	convert Converter[S, T]
+	sink    Sink[T]
+}
+
+// New will return a new generic Adapter struct
+func New[S, T any](source Source[S], convert Converter[S, T], sink Sink[T]) *Adapter[S, T] {
+	return &Adapter[S, T]{
+		source:  source,
+		convert: convert,
+		sink:    sink,
+	}
+}
+
+// Convert will drain the source converting every value into the sink. It stops at the
+// first error and returns the number of values written to the sink.
+func (a *Adapter[S, T]) Convert() (int, error) {
+	var (
+		converted int
+		err       error
+	)
+
+	sourceErr := a.source(func(value S) bool {
+		var target T
+		if target, err = a.convert(value); err != nil {
+			return false
+		}
+		if err = a.sink(target); err != nil {
+			return false
+		}
+		converted++
+		return true
+	})
+
+	if err != nil {
+		return converted, err
+	}
+	return converted, sourceErr
+}
+
+// SliceSource will return a Source yielding the values of the slice in order
+func SliceSource[S any](values []S) Source[S] {
+	return func(yield func(S) bool) error {
+		for _, value := range values {
+			if !yield(value) {
+				return nil
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,97 @@
+package adapter_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/patterns/adapter"
+)
+
+func TestGenericAdapterConvert(t *testing.T) {
+	errConvert := errors.New("convert failed")
+	errSink := errors.New("sink failed")
+	errSource := errors.New("source failed")
+
+	itoa := func(i int) (string, error) { return strconv.Itoa(i), nil }
+
+	tests := []struct {
+		name          string
+		source        adapter.Source[int]
+		convert       adapter.Converter[int, string]
+		sinkErrAt     string
+		wantConverted int
+		wantSunk      []string
+		wantErr       error
+	}{
+		{
+			name:          "ConvertsEveryValue",
+			source:        adapter.SliceSource([]int{1, 2, 3}),
+			convert:       itoa,
+			wantConverted: 3,
+			wantSunk:      []string{"1", "2", "3"},
+		},
+		{
+			name:    "EmptySource",
+			source:  adapter.SliceSource([]int{}),
+			convert: itoa,
+		},
+		{
+			name:   "ConverterErrorStops",
+			source: adapter.SliceSource([]int{1, 2, 3}),
+			convert: func(i int) (string, error) {
+				if i == 2 {
+					return "", errConvert
+				}
+				return strconv.Itoa(i), nil
+			},
+			wantConverted: 1,
+			wantSunk:      []string{"1"},
+			wantErr:       errConvert,
+		},
+		{
+			name:          "SinkErrorStops",
+			source:        adapter.SliceSource([]int{1, 2, 3}),
+			convert:       itoa,
+			sinkErrAt:     "2",
+			wantConverted: 1,
+			wantSunk:      []string{"1"},
+			wantErr:       errSink,
+		},
+		{
+			name: "SourceError",
+			source: func(yield func(int) bool) error {
+				yield(1)
+				return errSource
+			},
+			convert:       itoa,
+			wantConverted: 1,
+			wantSunk:      []string{"1"},
+			wantErr:       errSource,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sunk []string
+			sink := func(s string) error {
+				if s == tt.sinkErrAt {
+					return errSink
+				}
+				sunk = append(sunk, s)
+				return nil
+			}
+
+			converted, err := adapter.New(tt.source, tt.convert, sink).Convert()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Convert() error = %v, want %v", err, tt.wantErr)
+			}
+			if converted != tt.wantConverted {
+				t.Errorf("Convert() converted = %d, want %d", converted, tt.wantConverted)
+			}
+			if !equalSlice(sunk, tt.wantSunk) {
+				t.Errorf("sink got %v, want %v", sunk, tt.wantSunk)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package adapter
+
+// The HTTP legacy API adapts a remote legacy service instead of an in process one. The
+// legacy service speaks an old XML format over HTTP and the HTTPRecordsAPI client turns it
+// back into the plain records the adapter converts, so the adapter does not know the records
+// ever crossed the network. The client also writes records back which makes it usable on
+// the legacy side of the BidirectionalAdapter. LegacyService is a stub of such a service
+// run with httptest so the demo works offline.
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// xmlRecords is the XML document listing the records of the legacy service
+type xmlRecords struct {
+	XMLName xml.Name    `xml:"records"`
+	Records []xmlRecord `xml:"record"`
+}
+
+// xmlRecord is a single record of the legacy service XML format
+type xmlRecord struct {
+	XMLName xml.Name `xml:"record"`
+	ID      int      `xml:"id,attr"`
+	Value   string   `xml:",chardata"`
+}
+
+// HTTPRecordsAPI is the struct that reads the records from a legacy service over HTTP and
+// implements the WritableLegacyAPI and RecordStreamer interfaces
+type HTTPRecordsAPI struct {
+	baseURL string
+	client  *http.Client
+	err     error
+}
+
+// NewHTTPRecordsAPI will return a new HTTPRecordsAPI talking to the legacy service at the
+// base URL, a nil client uses its own client with a 5 second timeout
+func NewHTTPRecordsAPI(baseURL string, client *http.Client) *HTTPRecordsAPI {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &HTTPRecordsAPI{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  client,
+	}
+}
+
+// StreamRecords will decode the records of the legacy service one at a time and implements
+// the RecordStreamer interface
+func (h *HTTPRecordsAPI) StreamRecords(yield func(record string) bool) error {
+	resp, err := h.client.Get(h.baseURL + "/records")
+	if err != nil {
+		return errors.Wrap(err, "could not reach the legacy service")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("legacy service returned %s", resp.Status)
+	}
+
+	decoder := xml.NewDecoder(resp.Body)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "could not decode the legacy records")
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+
+		var record xmlRecord
+		if err := decoder.DecodeElement(&record, &start); err != nil {
+			return errors.Wrap(err, "could not decode the legacy records")
+		}
+		if !yield(record.Value) {
+			return nil
+		}
+	}
+}
+
+// Records will read every record of the legacy service and implements the LegacyAPI
+// interface. The interface has no room for an error so a failed read returns the records
+// read before the failure and the error is kept for Err. The RecordsAdapter and the
+// BidirectionalAdapter read through StreamRecords instead so they see the error.
+func (h *HTTPRecordsAPI) Records() []string {
+	var records []string
+	h.err = h.StreamRecords(func(record string) bool {
+		records = append(records, record)
+		return true
+	})
+	return records
+}
+
+// Err will return the error of the last call to Records
+func (h *HTTPRecordsAPI) Err() error {
+	return h.err
+}
+
+// SetRecord will overwrite the record at the index and implements the WritableLegacyAPI interface
+func (h *HTTPRecordsAPI) SetRecord(index int, record string) error {
+	return h.send(http.MethodPut, fmt.Sprintf("/records/%d", index), xmlRecord{ID: index, Value: record})
+}
+
+// AppendRecord will append a record and implements the WritableLegacyAPI interface
+func (h *HTTPRecordsAPI) AppendRecord(record string) error {
+	return h.send(http.MethodPost, "/records", xmlRecord{Value: record})
+}
+
+// send will send the record as XML to the path of the legacy service
+func (h *HTTPRecordsAPI) send(method string, path string, record xmlRecord) error {
+	body, err := xml.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(method, h.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/xml")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "could not reach the legacy service")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("legacy service returned %s", resp.Status)
+	}
+	return nil
+}
+
+// LegacyService is a stub of a legacy service serving its records in the old XML format
+//
+//	GET  /records         lists the records
+//	POST /records         appends the record of the body
+//	PUT  /records/{index} overwrites the record at the index
+type LegacyService struct {
+	mu      sync.Mutex
+	records []string
+}
+
+// NewLegacyService will return a new LegacyService holding the records
+func NewLegacyService(records ...string) *LegacyService {
+	return &LegacyService{records: records}
+}
+
+// StartLegacyStub will start a local httptest server running a LegacyService with the
+// records, the caller closes the server when done
+func StartLegacyStub(records ...string) *httptest.Server {
+	return httptest.NewServer(NewLegacyService(records...))
+}
+
+// ServeHTTP will serve the records and implements the http.Handler interface
+func (s *LegacyService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case path == "/records" && r.Method == http.MethodGet:
+		doc := xmlRecords{Records: make([]xmlRecord, len(s.records))}
+		for i, record := range s.records {
+			doc.Records[i] = xmlRecord{ID: i, Value: record}
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		io.WriteString(w, xml.Header)
+		xml.NewEncoder(w).Encode(doc)
+	case path == "/records" && r.Method == http.MethodPost:
+		record, ok := decodeRecord(w, r)
+		if !ok {
+			return
+		}
+		s.records = append(s.records, record.Value)
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, "/records/") && r.Method == http.MethodPut:
+		index, err := strconv.Atoi(strings.TrimPrefix(path, "/records/"))
+		if err != nil || index < 0 || index >= len(s.records) {
+			http.Error(w, "no such record", http.StatusNotFound)
+			return
+		}
+		record, ok := decodeRecord(w, r)
+		if !ok {
+			return
+		}
+		s.records[index] = record.Value
+	case path == "/records" || strings.HasPrefix(path, "/records/"):
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+/*##################################################################################
+# Helper Functions
+##################################################################################*/
+
+// decodeRecord will decode the XML record of the request body or answer with a bad request
+func decodeRecord(w http.ResponseWriter, r *http.Request) (xmlRecord, bool) {
+	var record xmlRecord
+	if err := xml.NewDecoder(r.Body).Decode(&record); err != nil {
+		http.Error(w, "malformed record: "+err.Error(), http.StatusBadRequest)
+		return record, false
+	}
+	return record, true
+}
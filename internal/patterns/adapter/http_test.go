@@ -0,0 +1,185 @@
+package adapter_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/patterns/adapter"
+)
+
+func TestHTTPRecordsAPIRecords(t *testing.T) {
+	server := adapter.StartLegacyStub("foo", "a <b> & c", "baz")
+	defer server.Close()
+
+	api := adapter.NewHTTPRecordsAPI(server.URL, server.Client())
+
+	if got := api.Records(); !equalSlice(got, []string{"foo", "a <b> & c", "baz"}) {
+		t.Errorf("Records() = %q", got)
+	}
+	if api.Err() != nil {
+		t.Errorf("Err() = %v", api.Err())
+	}
+
+	var streamed []string
+	err := api.StreamRecords(func(record string) bool {
+		streamed = append(streamed, record)
+		return len(streamed) < 2
+	})
+	if err != nil || !equalSlice(streamed, []string{"foo", "a <b> & c"}) {
+		t.Errorf("StreamRecords() stopped early got %q, %v", streamed, err)
+	}
+}
+
+func TestHTTPRecordsAPIErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		wantErr string
+	}{
+		{
+			name: "ServerError",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "down", http.StatusInternalServerError)
+			},
+			wantErr: "500 Internal Server Error",
+		},
+		{
+			name: "MalformedXML",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("<records><record id=\"0\">foo</records>"))
+			},
+			wantErr: "could not decode the legacy records",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			api := adapter.NewHTTPRecordsAPI(server.URL, server.Client())
+			api.Records()
+			if api.Err() == nil || !strings.Contains(api.Err().Error(), tt.wantErr) {
+				t.Errorf("Err() = %v, want it to contain %q", api.Err(), tt.wantErr)
+			}
+
+			// the adapter sees the error through StreamRecords
+			if err := adapter.NewAdapter(api, adapter.NewEntriesAPI()).ConvertRecords(); err == nil {
+				t.Errorf("ConvertRecords() error = nil, want the service error")
+			}
+		})
+	}
+
+	t.Run("Unreachable", func(t *testing.T) {
+		server := adapter.StartLegacyStub()
+		server.Close()
+
+		api := adapter.NewHTTPRecordsAPI(server.URL, nil)
+		if api.Records(); api.Err() == nil {
+			t.Errorf("Err() = nil, want the service to be unreachable")
+		}
+	})
+}
+
+// TestBidirectionalAdapterServiceDown checks the bidirectional adapter reports an unreachable
+// legacy service instead of treating it as a legacy side without records
+func TestBidirectionalAdapterServiceDown(t *testing.T) {
+	server := adapter.StartLegacyStub("foo", "bar")
+	modern := adapter.NewEntriesAPI()
+	bidi := adapter.NewBidirectionalAdapter(adapter.NewHTTPRecordsAPI(server.URL, server.Client()), modern,
+		adapter.WithKeyStrategy(adapter.ContentHashKeys()))
+
+	if _, err := bidi.Forward(); err != nil {
+		t.Fatalf("Forward() error = %v", err)
+	}
+	server.Close()
+	modern.AddEntry(key("bar"), "BAR")
+
+	tests := []struct {
+		name string
+		run  func() ([]adapter.Conflict, error)
+	}{
+		{"Forward", bidi.Forward},
+		{"WriteBack", bidi.WriteBack},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.run(); err == nil || !strings.Contains(err.Error(), "could not read legacy records") {
+				t.Errorf("%s() error = %v, want the service error", tt.name, err)
+			}
+		})
+	}
+}
+
+func TestHTTPRecordsAPIWrites(t *testing.T) {
+	server := adapter.StartLegacyStub("foo", "bar")
+	defer server.Close()
+
+	api := adapter.NewHTTPRecordsAPI(server.URL+"/", server.Client())
+
+	if err := api.SetRecord(1, "BAR"); err != nil {
+		t.Fatalf("SetRecord() error = %v", err)
+	}
+	if err := api.AppendRecord("qux"); err != nil {
+		t.Fatalf("AppendRecord() error = %v", err)
+	}
+	if err := api.SetRecord(5, "missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("SetRecord() out of range error = %v, want 404", err)
+	}
+
+	if got := api.Records(); !equalSlice(got, []string{"foo", "BAR", "qux"}) {
+		t.Errorf("Records() = %q", got)
+	}
+}
+
+func TestLegacyServiceRejectsBadRequests(t *testing.T) {
+	service := adapter.NewLegacyService("foo")
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"MalformedRecord", http.MethodPost, "/records", "not xml", http.StatusBadRequest},
+		{"BadIndex", http.MethodPut, "/records/x", "<record>v</record>", http.StatusNotFound},
+		{"WrongMethod", http.MethodDelete, "/records/0", "", http.StatusMethodNotAllowed},
+		{"UnknownPath", http.MethodGet, "/entries", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			service.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("%s %s status = %d, want %d", tt.method, tt.path, rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestBidirectionalAdapterOverHTTP(t *testing.T) {
+	server := adapter.StartLegacyStub("foo", "bar", "baz")
+	defer server.Close()
+
+	legacy := adapter.NewHTTPRecordsAPI(server.URL, server.Client())
+	modern := adapter.NewEntriesAPI()
+
+	b := adapter.NewBidirectionalAdapter(legacy, modern, adapter.WithKeyStrategy(adapter.ContentHashKeys()))
+
+	if _, err := b.Forward(); err != nil {
+		t.Fatalf("Forward() error = %v", err)
+	}
+	modern.AddEntry(key("bar"), "BAR")
+	if _, err := b.WriteBack(); err != nil {
+		t.Fatalf("WriteBack() error = %v", err)
+	}
+
+	if got := legacy.Records(); !equalSlice(got, []string{"foo", "BAR", "baz"}) {
+		t.Errorf("legacy service records = %q, want the modern change written back", got)
+	}
+}
@@ -0,0 +1,43 @@
+package adapter
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// keyNamespace is the UUID namespace of the content hash keys
+	keyNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/lkendrickd/patterns/adapter"))
+)
+
+// KeyStrategy returns the key a legacy record is stored under in the modern API
+// any function with this signature can be used as a caller supplied strategy
+type KeyStrategy func(record string) string
+
+// RandomKeys will return the strategy pairing every record with a new random UUID
+// converting the same records twice stores them twice
+func RandomKeys() KeyStrategy {
+	return func(string) string {
+		return uuid.NewString()
+	}
+}
+
+// ContentHashKeys will return the strategy deriving the key from the record itself as a
+// name based UUID (version 5, SHA-1). The same record always gets the same key so
+// converting the records again overwrites the entries instead of duplicating them.
+func ContentHashKeys() KeyStrategy {
+	return func(record string) string {
+		return uuid.NewSHA1(keyNamespace, []byte(record)).String()
+	}
+}
+
+// SequentialKeys will return the strategy numbering the records in the order they are
+// converted starting at 1. The keys are zero padded so they sort in conversion order.
+func SequentialKeys() KeyStrategy {
+	var n atomic.Int64
+	return func(string) string {
+		return fmt.Sprintf("%08d", n.Add(1))
+	}
+}
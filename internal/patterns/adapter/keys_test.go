@@ -0,0 +1,55 @@
+package adapter_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/patterns/adapter"
+)
+
+func TestKeyStrategies(t *testing.T) {
+	tests := []struct {
+		name        string
+		keys        adapter.KeyStrategy
+		wantEntries int // entries after converting the records twice
+		wantKey     string
+	}{
+		{"RandomDuplicates", adapter.RandomKeys(), 6, ""},
+		{"ContentHashIsIdempotent", adapter.ContentHashKeys(), 3, ""},
+		{"SequentialNumbersEveryConversion", adapter.SequentialKeys(), 6, "00000001"},
+		{"CallerSupplied", func(record string) string { return strings.ToUpper(record) }, 3, "FOO"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modernAPI := adapter.NewEntriesAPI()
+			adap := adapter.NewAdapter(adapter.NewRecordsAPI(), modernAPI, adapter.WithKeyStrategy(tt.keys))
+
+			for i := 0; i < 2; i++ {
+				if err := adap.ConvertRecords(); err != nil {
+					t.Fatalf("ConvertRecords() error = %v", err)
+				}
+			}
+
+			if got := len(modernAPI.Entries()); got != tt.wantEntries {
+				t.Errorf("got %d entries, want %d", got, tt.wantEntries)
+			}
+			if tt.wantKey != "" {
+				if _, ok := modernAPI.Entries()[tt.wantKey]; !ok {
+					t.Errorf("key %q not found in %v", tt.wantKey, modernAPI.Entries())
+				}
+			}
+		})
+	}
+}
+
+func TestContentHashKeysStable(t *testing.T) {
+	first, second := adapter.ContentHashKeys(), adapter.ContentHashKeys()
+
+	if first("foo") != second("foo") {
+		t.Errorf("ContentHashKeys() differ between strategies for the same record")
+	}
+	if first("foo") == first("bar") {
+		t.Errorf("ContentHashKeys() same key for different records")
+	}
+}
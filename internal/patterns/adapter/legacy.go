@@ -1,5 +1,11 @@
 package adapter
 
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
 // LegacyAPI the legacy API interface this represents the legacy API
 type LegacyAPI interface {
 	Records() []string
@@ -8,17 +14,99 @@ type LegacyAPI interface {
 // Records is the struct that holds the records amd implements the LegacyAPI interface
 type RecordsAPI struct {
 	records []string
+	logger  *slog.Logger
 }
 
-// NewRecordsAPI will return a new RecordsAPI struct
-func NewRecordsAPI() *RecordsAPI {
+// NewRecordsAPI will return a new RecordsAPI struct configured by the options
+// WithRecords, WithCapacity and WithLogger
+func NewRecordsAPI(opts ...RecordsOption) *RecordsAPI {
+	o := newOptions(opts, RecordsOption.applyRecords)
+
+	// inset some preexisting records symbolizing the legacy API data
+	records := []string{"foo", "bar", "baz"}
+	if o.records != nil {
+		records = o.records
+	}
+
 	return &RecordsAPI{
-		// inset some preexisting records symbolizing the legacy API data
-		records: []string{"foo", "bar", "baz"},
+		records: append(make([]string, 0, max(o.capacity, len(records))), records...),
+		logger:  o.logger,
 	}
 }
 
-// Records will return the records
+// Records will return a copy of the records
+// changes to the copy do not reach the RecordsAPI, records are only written by SetRecord and AppendRecord
 func (r *RecordsAPI) Records() []string {
-	return r.records
+	return slices.Clone(r.records)
+}
+
+// Snapshot will return a read only view of the records as they are now
+func (r *RecordsAPI) Snapshot() RecordsView {
+	return newRecordsView(r.records)
+}
+
+// Get will return the record at the index
+func (r *RecordsAPI) Get(index int) (string, bool) {
+	if index < 0 || index >= len(r.records) {
+		return "", false
+	}
+	return r.records[index], true
+}
+
+// Len will return the number of records
+func (r *RecordsAPI) Len() int {
+	return len(r.records)
+}
+
+// Range will call fn with every record in order until fn returns false
+func (r *RecordsAPI) Range(fn func(index int, record string) bool) {
+	r.Snapshot().Range(fn)
+}
+
+// SetRecord will overwrite the record at the index and implements the WritableLegacyAPI interface
+func (r *RecordsAPI) SetRecord(index int, record string) error {
+	if index < 0 || index >= len(r.records) {
+		return fmt.Errorf("record index %d out of range", index)
+	}
+
+	r.logger.Debug("record set", "index", index)
+	r.records[index] = record
+
+	return nil
+}
+
+// AppendRecord will append a record and implements the WritableLegacyAPI interface
+func (r *RecordsAPI) AppendRecord(record string) error {
+	r.logger.Debug("record appended", "index", len(r.records))
+	r.records = append(r.records, record)
+	return nil
+}
+
+// RowsAPI is the struct that holds the structured records of a legacy API as rows
+type RowsAPI struct {
+	columns []string
+	rows    []Row
+}
+
+// NewRowsAPI will return a new RowsAPI struct
+func NewRowsAPI() *RowsAPI {
+	return &RowsAPI{
+		// inset some preexisting rows symbolizing the structured legacy API data
+		columns: []string{"id", "full_name", "age", "vip", "country"},
+		rows: []Row{
+			{"1", "Ada Lovelace", "36", "true", "UK"},
+			{"2", "Alan Turing", "41", "", "UK"},
+			{"3", "Grace Hopper", "85", "false", ""},
+		},
+	}
+}
+
+// Columns will return the names of the columns of the rows
+func (r *RowsAPI) Columns() []string {
+	return r.columns
+}
+
+// Rows will return the rows
+func (r *RowsAPI) Rows() []Row {
+	return r.rows
 }
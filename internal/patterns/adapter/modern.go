@@ -1,6 +1,10 @@
 package adapter
 
-import "fmt"
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+)
 
 // ModernAPI the modern API interface this represents the modern API
 // note that this is a different interface than the legacy API and allows writes
@@ -9,32 +13,169 @@ type ModernAPI interface {
 	AddEntry(key string, value string) error
 }
 
+// EntryValidator is implemented by the modern APIs that can check an entry without storing it
+type EntryValidator interface {
+	ValidateEntry(key string, value string) error
+}
+
 // EntriesAPI is the struct that holds the entries amd implements the ModernAPI interface
+// it is safe for concurrent use and publishes every change to its subscribers
 type EntriesAPI struct {
-	entries map[string]string
+	mu        sync.RWMutex
+	entries   map[string]string
+	validator Validator
+	logger    *slog.Logger
+
+	// pending are the events of the writes in write order waiting to be delivered, they are
+	// queued under mu and delivered without it so a slow subscriber never blocks readers
+	pending    []pendingEvent
+	seq        uint64     // seq is the sequence number of the last queued event
+	delivered  uint64     // delivered is the sequence number of the last delivered event
+	delivering bool       // delivering is set while a writer delivers the pending events
+	published  *sync.Cond // published is signaled on mu when pending events were delivered
+
+	// publishMu guards the subscribers and is held while the events are delivered
+	publishMu   sync.Mutex
+	subscribers []*Subscription
+}
+
+// pendingEvent is an event waiting to be delivered with its sequence number
+type pendingEvent struct {
+	seq   uint64
+	event Event
 }
 
-// NewEntriesAPI will return a new EntriesAPI struct
-func NewEntriesAPI() *EntriesAPI {
-	return &EntriesAPI{
+// NewEntriesAPI will return a new EntriesAPI struct configured by the options
+// WithCapacity, WithValidator and WithLogger
+func NewEntriesAPI(opts ...EntriesOption) *EntriesAPI {
+	o := newOptions(opts, EntriesOption.applyEntries)
+	e := &EntriesAPI{
 		// initialize the entries map that is empty as it will hold the
 		// converted records from the legacy API
-		entries: make(map[string]string),
+		entries:   make(map[string]string, o.capacity),
+		validator: o.validator,
+		logger:    o.logger,
 	}
+	e.published = sync.NewCond(&e.mu)
+	return e
 }
 
-// Entries will return the entries and implements the ModernAPI interface
+// Entries will return a copy of the entries and implements the ModernAPI interface
+// changes to the copy do not reach the EntriesAPI, entries are only written by AddEntry
 func (e *EntriesAPI) Entries() map[string]string {
-	return e.entries
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return copyMap(e.entries)
+}
+
+// Snapshot will return a read only view of the entries as they are now
+func (e *EntriesAPI) Snapshot() EntriesView {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return newEntriesView(e.entries)
+}
+
+// Get will return the value of the key
+func (e *EntriesAPI) Get(key string) (string, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	value, ok := e.entries[key]
+	return value, ok
+}
+
+// Len will return the number of entries
+func (e *EntriesAPI) Len() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.entries)
+}
+
+// Keys will return the keys in sorted order
+func (e *EntriesAPI) Keys() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return sortedKeys(e.entries)
+}
+
+// Range will call fn with every entry in key order until fn returns false. It ranges over
+// a snapshot so fn may add or remove entries without deadlocking.
+func (e *EntriesAPI) Range(fn func(key string, value string) bool) {
+	e.Snapshot().Range(fn)
+}
+
+// ValidateEntry will return the error AddEntry would reject the entry with and implements
+// the EntryValidator interface
+func (e *EntriesAPI) ValidateEntry(key string, value string) error {
+	err := validateEntry(key, value)
+	if err == nil && e.validator != nil {
+		err = e.validator(key, value)
+	}
+	return err
 }
 
 // AddEntry will add an entry to the entries and implements the ModernAPI interface
 func (e *EntriesAPI) AddEntry(key string, value string) error {
-	if key == "" || value == "" {
-		return fmt.Errorf("key and value must not be empty")
+	if err := e.ValidateEntry(key, value); err != nil {
+		e.logger.Debug("entry rejected", "key", key, "error", err)
+		return err
 	}
 
+	e.mu.Lock()
+	previous, existed := e.entries[key]
 	e.entries[key] = value
 
+	switch {
+	case !existed:
+		e.notify(Event{Kind: EntryAdded, Key: key, Value: value})
+	case previous != value:
+		e.notify(Event{Kind: EntryUpdated, Key: key, Value: value, Previous: previous})
+	default:
+		e.mu.Unlock()
+	}
+
+	return nil
+}
+
+// RemoveEntry will remove the entry of the key and implements the EntryRemover interface
+func (e *EntriesAPI) RemoveEntry(key string) error {
+	e.mu.Lock()
+	previous, ok := e.entries[key]
+	if !ok {
+		e.mu.Unlock()
+		return fmt.Errorf("entry %q does not exist", key)
+	}
+
+	delete(e.entries, key)
+	e.notify(Event{Kind: EntryDeleted, Key: key, Previous: previous})
+
+	return nil
+}
+
+// lookupEntry will return the value of the key in the modern API without copying every
+// entry when the modern API can look up a single key
+func lookupEntry(modern ModernAPI, key string) (string, bool) {
+	if getter, ok := modern.(interface {
+		Get(key string) (string, bool)
+	}); ok {
+		return getter.Get(key)
+	}
+	value, ok := modern.Entries()[key]
+	return value, ok
+}
+
+// checkEntry will return the error the modern API would reject the entry with, a modern
+// API that is no EntryValidator is checked with the rules every modern API applies
+func checkEntry(modern ModernAPI, key string, value string) error {
+	if validator, ok := modern.(EntryValidator); ok {
+		return validator.ValidateEntry(key, value)
+	}
+	return validateEntry(key, value)
+}
+
+// validateEntry will return an error if the entry can not be stored in a modern API
+func validateEntry(key string, value string) error {
+	if key == "" || value == "" {
+		return fmt.Errorf("key and value must not be empty")
+	}
 	return nil
 }
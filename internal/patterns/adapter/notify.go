@@ -0,0 +1,289 @@
+package adapter
+
+// The change notifications are the observer pattern on top of the EntriesAPI. Every write
+// publishes an Event to the subscribers which receive them over a buffered channel or a
+// callback. The events are published in the order of the writes and a subscriber that can
+// not keep up is handled by its policy: its events are dropped, the writer waits for it or
+// it is disconnected. A write queues its event under the lock of the entries and delivers it
+// after releasing that lock, so a subscriber that holds up delivery can slow down writers
+// but never the readers of the entries.
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// EventKind is the kind of change an Event reports
+type EventKind int
+
+const (
+	// EntryAdded is a new key
+	EntryAdded EventKind = iota
+	// EntryUpdated is a new value of an existing key
+	EntryUpdated
+	// EntryDeleted is a removed key
+	EntryDeleted
+)
+
+// String will return the name of the kind
+func (k EventKind) String() string {
+	switch k {
+	case EntryUpdated:
+		return "updated"
+	case EntryDeleted:
+		return "deleted"
+	default:
+		return "added"
+	}
+}
+
+// Event is a change of an entry of the EntriesAPI
+type Event struct {
+	Kind     EventKind
+	Key      string
+	Value    string // Value is the new value, empty when the entry was deleted
+	Previous string // Previous is the value before the change, empty when the entry was added
+}
+
+// String will return a description of the event
+func (e Event) String() string {
+	switch e.Kind {
+	case EntryUpdated:
+		return fmt.Sprintf("%s %s: %s -> %s", e.Kind, e.Key, e.Previous, e.Value)
+	case EntryDeleted:
+		return fmt.Sprintf("%s %s: %s", e.Kind, e.Key, e.Previous)
+	default:
+		return fmt.Sprintf("%s %s: %s", e.Kind, e.Key, e.Value)
+	}
+}
+
+// SlowPolicy is what happens when the buffer of a subscriber is full
+type SlowPolicy int
+
+const (
+	// DropEvents drops the events the subscriber has no room for and counts them
+	DropEvents SlowPolicy = iota
+	// BlockWriter makes the writer wait until the subscriber has room, this slows
+	// every write down to the pace of the slowest subscriber. Reads are not slowed down.
+	// The OnChange callback of a BlockWriter subscription must not write to the same
+	// EntriesAPI as the write would wait for the subscription the callback is holding up.
+	BlockWriter
+	// Disconnect closes the subscription of the subscriber
+	Disconnect
+)
+
+// ErrSlowSubscriber is the error of a subscription closed by the Disconnect policy
+var ErrSlowSubscriber = errors.New("subscriber disconnected for falling behind")
+
+// SubscribeOptions are the options of a subscription
+type SubscribeOptions struct {
+	Buffer int        // Buffer is the number of events held for the subscriber
+	Policy SlowPolicy // Policy is what happens when the buffer is full
+}
+
+// Subscription is a subscriber of the EntriesAPI changes
+type Subscription struct {
+	// C delivers the events, it is closed when the subscription is closed
+	C <-chan Event
+
+	events  chan Event
+	policy  SlowPolicy
+	from    uint64 // from is the sequence number of the first event of the subscription
+	api     *EntriesAPI
+	done    chan struct{} // done is closed when the subscription is closed
+	stopped chan struct{} // stopped is closed when the callback returned, nil without one
+	dropped atomic.Int64
+	once    sync.Once
+	err     error
+}
+
+// Subscribe will return a new Subscription receiving the changes made after it
+func (e *EntriesAPI) Subscribe(opts SubscribeOptions) *Subscription {
+	events := make(chan Event, opts.Buffer)
+	s := &Subscription{
+		C:      events,
+		events: events,
+		policy: opts.Policy,
+		api:    e,
+		done:   make(chan struct{}),
+	}
+
+	// the events queued before the subscription are not delivered to it
+	e.mu.RLock()
+	s.from = e.seq + 1
+	e.mu.RUnlock()
+
+	e.publishMu.Lock()
+	defer e.publishMu.Unlock()
+	e.subscribers = append(e.subscribers, s)
+
+	return s
+}
+
+// OnChange will return a new Subscription calling fn with every change made after it.
+// fn runs on its own goroutine one event at a time, Close waits for it to return so fn
+// must not close its own subscription. fn may write to the EntriesAPI unless the policy
+// is BlockWriter.
+func (e *EntriesAPI) OnChange(opts SubscribeOptions, fn func(Event)) *Subscription {
+	s := e.Subscribe(opts)
+	s.stopped = make(chan struct{})
+
+	go func() {
+		defer close(s.stopped)
+		for event := range s.C {
+			fn(event)
+		}
+	}()
+
+	return s
+}
+
+// Close will stop the subscription and close its channel, the events already buffered
+// can still be received. It waits for the callback of an OnChange subscription to finish.
+func (s *Subscription) Close() {
+	s.close(nil)
+	if s.stopped != nil {
+		<-s.stopped
+	}
+}
+
+// Dropped will return the number of events dropped by the DropEvents policy
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Err will return ErrSlowSubscriber when the subscription was closed by the Disconnect policy
+// it returns nil while the subscription is open
+func (s *Subscription) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// close will close the subscription once recording why
+func (s *Subscription) close(err error) {
+	s.once.Do(func() {
+		s.err = err
+		// release a writer blocked on this subscriber before taking the publish lock it holds
+		close(s.done)
+
+		s.api.publishMu.Lock()
+		defer s.api.publishMu.Unlock()
+		s.api.unsubscribe(s)
+		close(s.events)
+	})
+}
+
+// deliver will hand the event to the subscriber according to its policy and report whether
+// the subscriber should be disconnected, it is called with the publish lock held
+func (s *Subscription) deliver(p pendingEvent) bool {
+	if p.seq < s.from {
+		return false
+	}
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+
+	event := p.event
+	switch s.policy {
+	case BlockWriter:
+		select {
+		case s.events <- event:
+		case <-s.done:
+		}
+	case Disconnect:
+		select {
+		case s.events <- event:
+		default:
+			return true
+		}
+	default:
+		select {
+		case s.events <- event:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+	return false
+}
+
+/*##################################################################################
+# Helper Functions
+##################################################################################*/
+
+// notify will queue the event of a write and wait until it was delivered. It is called with
+// mu held and releases it. The first writer to find no delivery in progress delivers the
+// queued events of every writer in order, the others wait on the published condition which
+// releases mu so readers go on while a subscriber holds up the delivery.
+func (e *EntriesAPI) notify(event Event) {
+	e.seq++
+	seq := e.seq
+	e.pending = append(e.pending, pendingEvent{seq: seq, event: event})
+
+	if e.delivering {
+		for e.delivered < seq {
+			e.published.Wait()
+		}
+		e.mu.Unlock()
+		return
+	}
+
+	e.delivering = true
+	for len(e.pending) > 0 {
+		batch := e.pending
+		e.pending = nil
+		e.mu.Unlock()
+
+		var slow []*Subscription
+		e.publishMu.Lock()
+		for _, p := range batch {
+			slow = append(slow, e.publish(p)...)
+		}
+		e.publishMu.Unlock()
+
+		// close the slow subscribers without the publish lock close takes
+		for _, s := range slow {
+			s.close(ErrSlowSubscriber)
+		}
+
+		e.mu.Lock()
+		e.delivered = batch[len(batch)-1].seq
+		e.published.Broadcast()
+	}
+	e.delivering = false
+	e.mu.Unlock()
+}
+
+// publish will deliver the event to every subscriber and return the slow ones to disconnect.
+// It is called with the publish lock held by the single writer delivering so the events
+// reach the subscribers in the order of the writes. The slow subscribers are unsubscribed
+// so they get no further events and are closed by the caller once it released the lock.
+func (e *EntriesAPI) publish(p pendingEvent) []*Subscription {
+	var slow []*Subscription
+	for _, s := range e.subscribers {
+		if s.deliver(p) {
+			slow = append(slow, s)
+		}
+	}
+	for _, s := range slow {
+		e.unsubscribe(s)
+	}
+	return slow
+}
+
+// unsubscribe will remove the subscriber, it is called with the publish lock held
+func (e *EntriesAPI) unsubscribe(s *Subscription) {
+	for i, subscriber := range e.subscribers {
+		if subscriber == s {
+			e.subscribers = append(e.subscribers[:i], e.subscribers[i+1:]...)
+			return
+		}
+	}
+}
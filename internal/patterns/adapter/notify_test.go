@@ -0,0 +1,299 @@
+package adapter_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lkendrickd/patterns/internal/patterns/adapter"
+)
+
+// drain will receive every event until the subscription channel is closed
+func drain(s *adapter.Subscription) []string {
+	var events []string
+	for event := range s.C {
+		events = append(events, event.String())
+	}
+	return events
+}
+
+func TestSubscribeEvents(t *testing.T) {
+	api := adapter.NewEntriesAPI()
+	api.AddEntry("before", "not seen")
+
+	sub := api.Subscribe(adapter.SubscribeOptions{Buffer: 10})
+
+	api.AddEntry("k1", "v1")
+	api.AddEntry("k1", "v1") // the same value is no change
+	api.AddEntry("k1", "v2")
+	api.RemoveEntry("k1")
+	api.RemoveEntry("k1") // removing a missing key fails without an event
+	sub.Close()
+	api.AddEntry("after", "not seen")
+
+	want := []string{"added k1: v1", "updated k1: v1 -> v2", "deleted k1: v2"}
+	if got := drain(sub); !equalSlice(got, want) {
+		t.Errorf("events got %v, want %v", got, want)
+	}
+	if sub.Err() != nil {
+		t.Errorf("Err() = %v, want nil after Close", sub.Err())
+	}
+}
+
+func TestSlowSubscriberPolicies(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      adapter.SlowPolicy
+		wantEvents  []string
+		wantDropped int64
+		wantErr     error
+	}{
+		{
+			name:        "DropEvents",
+			policy:      adapter.DropEvents,
+			wantEvents:  []string{"added k1: v", "added k2: v"},
+			wantDropped: 2,
+		},
+		{
+			name:       "Disconnect",
+			policy:     adapter.Disconnect,
+			wantEvents: []string{"added k1: v", "added k2: v"},
+			wantErr:    adapter.ErrSlowSubscriber,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := adapter.NewEntriesAPI()
+			sub := api.Subscribe(adapter.SubscribeOptions{Buffer: 2, Policy: tt.policy})
+
+			for i := 1; i <= 4; i++ {
+				api.AddEntry(fmt.Sprintf("k%d", i), "v")
+			}
+			sub.Close()
+
+			if got := drain(sub); !equalSlice(got, tt.wantEvents) {
+				t.Errorf("events got %v, want %v", got, tt.wantEvents)
+			}
+			if sub.Dropped() != tt.wantDropped {
+				t.Errorf("Dropped() = %d, want %d", sub.Dropped(), tt.wantDropped)
+			}
+			if !errors.Is(sub.Err(), tt.wantErr) {
+				t.Errorf("Err() = %v, want %v", sub.Err(), tt.wantErr)
+			}
+			if api.Len() != 4 {
+				t.Errorf("Len() = %d, want every write to succeed", api.Len())
+			}
+		})
+	}
+}
+
+func TestBlockWriterWaitsForSubscriber(t *testing.T) {
+	api := adapter.NewEntriesAPI()
+	sub := api.Subscribe(adapter.SubscribeOptions{Policy: adapter.BlockWriter})
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		api.AddEntry("k1", "v1")
+	}()
+
+	select {
+	case <-written:
+		t.Fatal("AddEntry() returned before the subscriber received the event")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if event := <-sub.C; event.Kind != adapter.EntryAdded || event.Key != "k1" {
+		t.Errorf("received %v, want added k1", event)
+	}
+	<-written
+
+	// closing releases a writer blocked on the subscriber
+	go api.AddEntry("k2", "v2")
+	time.Sleep(10 * time.Millisecond)
+	sub.Close()
+	drain(sub)
+}
+
+func TestOnChangeCallback(t *testing.T) {
+	api := adapter.NewEntriesAPI()
+
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	sub := api.OnChange(adapter.SubscribeOptions{Buffer: 1, Policy: adapter.BlockWriter}, func(event adapter.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event.String())
+	})
+
+	for i := 0; i < 50; i++ {
+		api.AddEntry("k", fmt.Sprint(i))
+	}
+	sub.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 50 || events[0] != "added k: 0" || events[49] != "updated k: 48 -> 49" {
+		t.Errorf("callback got %d events, first %q last %q", len(events), events[0], events[len(events)-1])
+	}
+}
+
+func TestConcurrentWritersPublishInOrder(t *testing.T) {
+	api := adapter.NewEntriesAPI()
+	sub := api.Subscribe(adapter.SubscribeOptions{Buffer: 1, Policy: adapter.BlockWriter})
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				api.AddEntry("shared", fmt.Sprintf("%d-%d", w, i))
+			}
+		}(w)
+	}
+	go func() {
+		wg.Wait()
+		sub.Close()
+	}()
+
+	// every update must start from the value of the event before it
+	previous := ""
+	for event := range sub.C {
+		if event.Previous != previous {
+			t.Fatalf("event %v does not follow value %q", event, previous)
+		}
+		previous = event.Value
+	}
+	if value, _ := api.Get("shared"); value != previous {
+		t.Errorf("last event value %q, want the stored value %q", previous, value)
+	}
+}
+
+func TestBlockWriterDoesNotBlockReaders(t *testing.T) {
+	api := adapter.NewEntriesAPI()
+	sub := api.Subscribe(adapter.SubscribeOptions{Policy: adapter.BlockWriter})
+
+	// the first writer is stuck delivering to the subscriber, the second waits behind it
+	var wg sync.WaitGroup
+	for _, key := range []string{"k1", "k2"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			api.AddEntry(key, "v")
+		}(key)
+	}
+
+	read := make(chan int)
+	go func() {
+		// wait for both writes to be stored before reading
+		for api.Len() < 2 {
+			time.Sleep(time.Millisecond)
+		}
+		api.Get("k1")
+		api.Entries()
+		read <- api.Len()
+	}()
+
+	select {
+	case n := <-read:
+		if n != 2 {
+			t.Errorf("Len() = %d, want 2", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("readers blocked by a writer waiting for the subscriber")
+	}
+
+	for i := 0; i < 2; i++ {
+		<-sub.C
+	}
+	wg.Wait()
+	sub.Close()
+}
+
+func TestOnChangeCallbackWrites(t *testing.T) {
+	api := adapter.NewEntriesAPI()
+
+	done := make(chan struct{})
+	sub := api.OnChange(adapter.SubscribeOptions{Buffer: 4}, func(event adapter.Event) {
+		// mirror every entry under a second key from the callback
+		if event.Key == "k" {
+			api.AddEntry("mirror", event.Value)
+			close(done)
+		}
+	})
+	defer sub.Close()
+
+	api.AddEntry("k", "v")
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a callback writing to the entries deadlocked")
+	}
+	if value, _ := api.Get("mirror"); value != "v" {
+		t.Errorf("Get(mirror) = %q, want v", value)
+	}
+}
+
+func TestSubscriptionErrWhileOpen(t *testing.T) {
+	api := adapter.NewEntriesAPI()
+	sub := api.Subscribe(adapter.SubscribeOptions{Buffer: 1})
+
+	done := make(chan error)
+	go func() { done <- sub.Err() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Err() = %v, want nil while open", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Err() blocked on an open subscription")
+	}
+	sub.Close()
+}
+
+// TestCloseRacesDisconnect closes subscriptions while a write disconnects them, run it with -race
+func TestCloseRacesDisconnect(t *testing.T) {
+	api := adapter.NewEntriesAPI()
+
+	for i := 0; i < 200; i++ {
+		// without a buffer every event disconnects the subscriber
+		sub := api.Subscribe(adapter.SubscribeOptions{Policy: adapter.Disconnect})
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			api.AddEntry("k", fmt.Sprint(i))
+		}(i)
+		go func() {
+			defer wg.Done()
+			sub.Close()
+		}()
+
+		finished := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(finished)
+		}()
+		select {
+		case <-finished:
+		case <-time.After(time.Second):
+			t.Fatalf("Close() and a disconnecting write deadlocked on iteration %d", i)
+		}
+
+		if err := sub.Err(); err != nil && !errors.Is(err, adapter.ErrSlowSubscriber) {
+			t.Fatalf("Err() = %v", err)
+		}
+	}
+
+	// later writes are not stuck behind the deadlocked delivery
+	if err := api.AddEntry("after", "v"); err != nil {
+		t.Errorf("AddEntry() error = %v", err)
+	}
+}
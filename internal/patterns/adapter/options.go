@@ -0,0 +1,190 @@
+package adapter
+
+// The constructors of the package are configured with functional options. Every option
+// sets one field of a shared options struct and each constructor reads the fields that
+// apply to what it builds, so a new behavior is a new option rather than a new constructor
+// and the same option such as WithLogger configures every type it makes sense for.
+// Each constructor takes its own option type and an option implements the types of the
+// constructors it applies to, so an option passed to a constructor that would ignore it
+// does not compile.
+
+import (
+	"io"
+	"log/slog"
+	"slices"
+)
+
+// AdapterOption configures NewAdapter
+type AdapterOption interface {
+	applyAdapter(o *options)
+}
+
+// BidirectionalOption configures NewBidirectionalAdapter
+type BidirectionalOption interface {
+	applyBidirectional(o *options)
+}
+
+// EntriesOption configures NewEntriesAPI
+type EntriesOption interface {
+	applyEntries(o *options)
+}
+
+// RecordsOption configures NewRecordsAPI
+type RecordsOption interface {
+	applyRecords(o *options)
+}
+
+// KeyStrategyOption is the option of WithKeyStrategy, it configures NewAdapter and
+// NewBidirectionalAdapter
+type KeyStrategyOption func(*options)
+
+func (f KeyStrategyOption) applyAdapter(o *options)       { f(o) }
+func (f KeyStrategyOption) applyBidirectional(o *options) { f(o) }
+
+// LoggerOption is the option of WithLogger, it configures NewAdapter, NewEntriesAPI and
+// NewRecordsAPI
+type LoggerOption func(*options)
+
+func (f LoggerOption) applyAdapter(o *options) { f(o) }
+func (f LoggerOption) applyEntries(o *options) { f(o) }
+func (f LoggerOption) applyRecords(o *options) { f(o) }
+
+// CapacityOption is the option of WithCapacity, it configures NewEntriesAPI and NewRecordsAPI
+type CapacityOption func(*options)
+
+func (f CapacityOption) applyEntries(o *options) { f(o) }
+func (f CapacityOption) applyRecords(o *options) { f(o) }
+
+// adapterOption is an option only NewAdapter takes
+type adapterOption func(*options)
+
+func (f adapterOption) applyAdapter(o *options) { f(o) }
+
+// entriesOption is an option only NewEntriesAPI takes
+type entriesOption func(*options)
+
+func (f entriesOption) applyEntries(o *options) { f(o) }
+
+// recordsOption is an option only NewRecordsAPI takes
+type recordsOption func(*options)
+
+func (f recordsOption) applyRecords(o *options) { f(o) }
+
+// Validator checks an entry before the modern API stores it
+type Validator func(key string, value string) error
+
+// options are the settings the options configure
+type options struct {
+	keys      KeyStrategy
+	errorMode ErrorMode
+	pipeline  Pipeline
+	auditSink AuditSink
+	logger    *slog.Logger
+	records   []string
+	capacity  int
+	validator Validator
+}
+
+// WithKeyStrategy will set the strategy generating the keys of the converted records
+// it applies to NewAdapter and NewBidirectionalAdapter which use RandomKeys without one
+func WithKeyStrategy(keys KeyStrategy) KeyStrategyOption {
+	return func(o *options) {
+		o.keys = keys
+	}
+}
+
+// WithErrorMode will set how NewAdapter handles the records the modern API rejects
+// the adapter fails fast without one
+func WithErrorMode(mode ErrorMode) AdapterOption {
+	return adapterOption(func(o *options) {
+		o.errorMode = mode
+	})
+}
+
+// WithPipeline will set the pipeline NewAdapter passes the legacy records through before
+// they are converted. The pipeline needs every record at once so with a pipeline set the
+// streaming conversion no longer holds only one batch in memory.
+func WithPipeline(pipeline Pipeline) AdapterOption {
+	return adapterOption(func(o *options) {
+		o.pipeline = pipeline
+	})
+}
+
+// WithAuditSink will set the sink NewAdapter reports every legacy record to
+func WithAuditSink(sink AuditSink) AdapterOption {
+	return adapterOption(func(o *options) {
+		o.auditSink = sink
+	})
+}
+
+// WithLogger will set the logger, it applies to NewAdapter, NewEntriesAPI and NewRecordsAPI
+// which log nothing without one
+func WithLogger(logger *slog.Logger) LoggerOption {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithRecords will set the records NewRecordsAPI starts with instead of the sample records
+func WithRecords(records ...string) RecordsOption {
+	return recordsOption(func(o *options) {
+		o.records = slices.Clone(records)
+		if o.records == nil {
+			o.records = []string{}
+		}
+	})
+}
+
+// WithCapacity will set the number of entries NewEntriesAPI or records NewRecordsAPI
+// allocate room for up front
+func WithCapacity(capacity int) CapacityOption {
+	return func(o *options) {
+		o.capacity = capacity
+	}
+}
+
+// WithValidator will add a check NewEntriesAPI runs on every entry after the built in
+// check that neither key nor value is empty. The checks of several WithValidator options
+// run in order and the first error rejects the entry, a nil validator adds nothing.
+func WithValidator(validator Validator) EntriesOption {
+	return entriesOption(func(o *options) {
+		if validator == nil {
+			return
+		}
+		previous := o.validator
+		if previous == nil {
+			o.validator = validator
+			return
+		}
+		o.validator = func(key string, value string) error {
+			if err := previous(key, value); err != nil {
+				return err
+			}
+			return validator(key, value)
+		}
+	})
+}
+
+/*##################################################################################
+# Helper Functions
+##################################################################################*/
+
+// newOptions will return the defaults with the options applied in order, apply is the
+// method applying an option of the constructor such as AdapterOption.applyAdapter
+func newOptions[O any](opts []O, apply func(O, *options)) options {
+	var o options
+	for _, opt := range opts {
+		apply(opt, &o)
+	}
+	// a nil option value keeps the default
+	if o.keys == nil {
+		o.keys = RandomKeys()
+	}
+	if o.logger == nil {
+		o.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	if o.capacity < 0 {
+		o.capacity = 0
+	}
+	return o
+}
@@ -0,0 +1,247 @@
+package adapter_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/patterns/adapter"
+)
+
+func TestNewAdapterOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		records     []string
+		opts        []adapter.AdapterOption
+		wantErr     bool
+		wantEntries map[string]string
+	}{
+		{
+			name:        "WithKeyStrategy",
+			records:     []string{"foo", "bar"},
+			opts:        []adapter.AdapterOption{adapter.WithKeyStrategy(adapter.SequentialKeys())},
+			wantEntries: map[string]string{"00000001": "foo", "00000002": "bar"},
+		},
+		{
+			name:    "WithPipeline",
+			records: []string{" foo ", " "},
+			opts: []adapter.AdapterOption{
+				adapter.WithKeyStrategy(adapter.SequentialKeys()),
+				adapter.WithPipeline(adapter.NewPipeline(adapter.TrimSpace(), dropEmpty)),
+			},
+			wantEntries: map[string]string{"00000001": "foo"},
+		},
+		{
+			name:    "WithErrorMode",
+			records: []string{"foo", "", "bar"},
+			opts: []adapter.AdapterOption{
+				adapter.WithKeyStrategy(adapter.SequentialKeys()),
+				adapter.WithErrorMode(adapter.SkipAndCollect),
+			},
+			wantErr:     true,
+			wantEntries: map[string]string{"00000001": "foo", "00000003": "bar"},
+		},
+		{
+			name:    "NilKeyStrategyKeepsDefault",
+			records: []string{"foo"},
+			opts:    []adapter.AdapterOption{adapter.WithKeyStrategy(nil), adapter.WithLogger(nil)},
+		},
+		{
+			name:    "LaterOptionWins",
+			records: []string{"foo"},
+			opts: []adapter.AdapterOption{
+				adapter.WithKeyStrategy(adapter.RandomKeys()),
+				adapter.WithKeyStrategy(adapter.SequentialKeys()),
+			},
+			wantEntries: map[string]string{"00000001": "foo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modern := adapter.NewEntriesAPI()
+			adap := adapter.NewAdapter(adapter.NewRecordsAPI(adapter.WithRecords(tt.records...)), modern, tt.opts...)
+
+			if err := adap.ConvertRecords(); (err != nil) != tt.wantErr {
+				t.Fatalf("ConvertRecords() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantEntries == nil {
+				if modern.Len() != len(tt.records) {
+					t.Errorf("Len() = %d, want %d", modern.Len(), len(tt.records))
+				}
+				return
+			}
+			if !equalMap(modern.Entries(), tt.wantEntries) {
+				t.Errorf("Entries() = %v, want %v", modern.Entries(), tt.wantEntries)
+			}
+		})
+	}
+}
+
+func TestWithAuditSinkAndLogger(t *testing.T) {
+	var logs bytes.Buffer
+	audit := adapter.NewMemoryAudit()
+
+	adap := adapter.NewAdapter(adapter.NewRecordsAPI(), adapter.NewEntriesAPI(),
+		adapter.WithAuditSink(audit),
+		adapter.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	)
+	if err := adap.ConvertRecords(); err != nil {
+		t.Fatalf("ConvertRecords() error = %v", err)
+	}
+
+	if audit.Report().Converted != 3 {
+		t.Errorf("audit report = %+v, want 3 converted", audit.Report())
+	}
+	if !strings.Contains(logs.String(), "records converted") || !strings.Contains(logs.String(), "records=3") {
+		t.Errorf("logs = %q, want the conversion logged", logs.String())
+	}
+}
+
+func TestNewEntriesAPIOptions(t *testing.T) {
+	var logs bytes.Buffer
+	api := adapter.NewEntriesAPI(
+		adapter.WithCapacity(16),
+		adapter.WithValidator(func(key, value string) error {
+			if len(value) > 3 {
+				return errors.New("value too long")
+			}
+			return nil
+		}),
+		adapter.WithLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+	)
+
+	if err := api.AddEntry("k1", "foo"); err != nil {
+		t.Errorf("AddEntry() error = %v", err)
+	}
+	if err := api.AddEntry("k2", "toolong"); err == nil || err.Error() != "value too long" {
+		t.Errorf("AddEntry() error = %v, want the validator error", err)
+	}
+	// the built in check still runs before the validator
+	if err := api.AddEntry("", "foo"); err == nil {
+		t.Errorf("AddEntry() with empty key error = nil")
+	}
+	if api.Len() != 1 {
+		t.Errorf("Len() = %d, want only the valid entry", api.Len())
+	}
+	if !strings.Contains(logs.String(), "entry rejected") {
+		t.Errorf("logs = %q, want the rejected entries logged", logs.String())
+	}
+
+	if api := adapter.NewEntriesAPI(adapter.WithCapacity(-1)); api.Len() != 0 {
+		t.Errorf("NewEntriesAPI() with a negative capacity has %d entries", api.Len())
+	}
+}
+
+func TestNewRecordsAPIOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []adapter.RecordsOption
+		want []string
+	}{
+		{"SampleRecords", nil, []string{"foo", "bar", "baz"}},
+		{"WithRecords", []adapter.RecordsOption{adapter.WithRecords("a", "b")}, []string{"a", "b"}},
+		{"WithNoRecords", []adapter.RecordsOption{adapter.WithRecords()}, []string{}},
+		{"WithCapacity", []adapter.RecordsOption{adapter.WithCapacity(100), adapter.WithRecords("a")}, []string{"a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := adapter.NewRecordsAPI(tt.opts...).Records(); !equalSlice(got, tt.want) {
+				t.Errorf("Records() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// the records are copied so the caller's slice does not share the RecordsAPI storage
+	records := []string{"a", "b"}
+	api := adapter.NewRecordsAPI(adapter.WithRecords(records...))
+	records[0] = "changed"
+	if got, _ := api.Get(0); got != "a" {
+		t.Errorf("Get(0) = %q, WithRecords did not copy the records", got)
+	}
+}
+
+func TestNewBidirectionalAdapterOptions(t *testing.T) {
+	modern := adapter.NewEntriesAPI()
+	b := adapter.NewBidirectionalAdapter(adapter.NewRecordsAPI(adapter.WithRecords("foo")), modern,
+		adapter.WithKeyStrategy(adapter.ContentHashKeys()))
+
+	if _, err := b.Forward(); err != nil {
+		t.Fatalf("Forward() error = %v", err)
+	}
+	if _, ok := modern.Get(key("foo")); !ok {
+		t.Errorf("Entries() = %v, want the content hash key of foo", modern.Entries())
+	}
+}
+
+func TestOptionsApplyTo(t *testing.T) {
+	tests := []struct {
+		name                                  string
+		opt                                   any
+		adapter, bidirectional, entries, recs bool
+	}{
+		{"WithKeyStrategy", adapter.WithKeyStrategy(adapter.SequentialKeys()), true, true, false, false},
+		{"WithErrorMode", adapter.WithErrorMode(adapter.SkipAndCollect), true, false, false, false},
+		{"WithPipeline", adapter.WithPipeline(adapter.NewPipeline()), true, false, false, false},
+		{"WithAuditSink", adapter.WithAuditSink(adapter.NewMemoryAudit()), true, false, false, false},
+		{"WithLogger", adapter.WithLogger(nil), true, false, true, true},
+		{"WithRecords", adapter.WithRecords("a"), false, false, false, true},
+		{"WithCapacity", adapter.WithCapacity(1), false, false, true, true},
+		{"WithValidator", adapter.WithValidator(nil), false, false, true, false},
+	}
+
+	// an option that does not apply to a constructor can not be passed to it
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := tt.opt.(adapter.AdapterOption); ok != tt.adapter {
+				t.Errorf("AdapterOption = %v, want %v", ok, tt.adapter)
+			}
+			if _, ok := tt.opt.(adapter.BidirectionalOption); ok != tt.bidirectional {
+				t.Errorf("BidirectionalOption = %v, want %v", ok, tt.bidirectional)
+			}
+			if _, ok := tt.opt.(adapter.EntriesOption); ok != tt.entries {
+				t.Errorf("EntriesOption = %v, want %v", ok, tt.entries)
+			}
+			if _, ok := tt.opt.(adapter.RecordsOption); ok != tt.recs {
+				t.Errorf("RecordsOption = %v, want %v", ok, tt.recs)
+			}
+		})
+	}
+}
+
+func TestWithValidatorComposes(t *testing.T) {
+	maxLength := adapter.WithValidator(func(key, value string) error {
+		if len(value) > 3 {
+			return errors.New("value too long")
+		}
+		return nil
+	})
+	noDigits := adapter.WithValidator(func(key, value string) error {
+		if strings.ContainsAny(value, "0123456789") {
+			return errors.New("digits are not allowed")
+		}
+		return nil
+	})
+	api := adapter.NewEntriesAPI(maxLength, adapter.WithValidator(nil), noDigits)
+
+	tests := []struct {
+		value   string
+		wantErr string
+	}{
+		{"foo", ""},
+		{"toolong", "value too long"},
+		{"b4r", "digits are not allowed"},
+		// the first validator rejects the entry before the second runs
+		{"t00long", "value too long"},
+	}
+	for _, tt := range tests {
+		err := api.AddEntry("k", tt.value)
+		if gotErr := fmt.Sprint(err); (err == nil) != (tt.wantErr == "") || (err != nil && gotErr != tt.wantErr) {
+			t.Errorf("AddEntry(%q) error = %v, want %q", tt.value, err, tt.wantErr)
+		}
+	}
+}
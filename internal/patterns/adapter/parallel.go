@@ -0,0 +1,188 @@
+package adapter
+
+// The parallel conversion fans the legacy records out to a pool of workers. Without order
+// the workers write to the modern API themselves so the modern API must be safe for
+// concurrent use. With order the workers only convert and a single writer puts the entries
+// back in source order before writing them. Either way the writes can be rate limited.
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MaxRateLimit is the highest RateLimit, one write per nanosecond is the finest interval
+// the rate limiting ticker can tick at
+const MaxRateLimit = int(time.Second)
+
+// ParallelOptions are the options of a parallel conversion
+type ParallelOptions struct {
+	Workers int  // Workers is the number of records converted at the same time
+	Ordered bool // Ordered writes the entries in the order of the legacy records
+	// RateLimit is the maximum writes per second to the modern API from 0 which is unlimited
+	// up to MaxRateLimit
+	RateLimit int
+}
+
+// job is a legacy record handed to a worker
+type job struct {
+	index  int
+	record string
+}
+
+// result is a converted record handed back by a worker
+type result struct {
+	index int
+	entry Entry
+	err   error
+}
+
+// ConvertParallel will convert the legacy records with a pool of workers. In FailFast mode the
+// first failure stops the conversion, records already in flight may still be written when
+// the output is unordered. Transactional mode is not supported.
+func (a *RecordsAdapter) ConvertParallel(opts ParallelOptions) error {
+	if opts.Workers < 1 {
+		return errors.New("at least one worker is required")
+	}
+	if a.errorMode == Transactional {
+		return errors.New("transactional mode is not supported in parallel")
+	}
+	if opts.RateLimit < 0 || opts.RateLimit > MaxRateLimit {
+		return errors.Errorf("rate limit %d is out of range, it must be between 0 and %d writes per second",
+			opts.RateLimit, MaxRateLimit)
+	}
+
+	// the ticker channel is shared by every writer so together they stay under the limit
+	var limiter <-chan time.Time
+	if opts.RateLimit > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(opts.RateLimit))
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+
+	write := func(index int, entry Entry) error {
+		if limiter != nil {
+			<-limiter
+		}
+		if err := a.addEntry(entry); err != nil {
+			a.audit(index, entry, OutcomeFailed, err)
+			return a.recordError(index, entry.Value, err)
+		}
+		a.audit(index, entry, OutcomeConverted, nil)
+		return nil
+	}
+
+	var (
+		done     = make(chan struct{})
+		stopOnce sync.Once
+		stop     = func() { stopOnce.Do(func() { close(done) }) }
+		jobs     = make(chan job)
+		results  = make(chan result)
+	)
+	defer stop()
+
+	// produce the jobs from the legacy records until they run out or the conversion stops
+	var (
+		total     int
+		sourceErr error
+		produced  = make(chan struct{})
+	)
+	go func() {
+		defer close(produced)
+		defer close(jobs)
+		sourceErr = a.records(func(record string) bool {
+			select {
+			case jobs <- job{index: total, record: record}:
+				total++
+				return true
+			case <-done:
+				return false
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				entry, err := a.toEntry(j.record)
+				if err != nil {
+					err = a.recordError(j.index, j.record, err)
+				} else if !opts.Ordered {
+					err = write(j.index, entry)
+				}
+
+				select {
+				case results <- result{index: j.index, entry: entry, err: err}:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var (
+		failed   ConversionErrors
+		firstErr error
+	)
+	handle := func(r result) {
+		if r.err == nil {
+			return
+		}
+		if a.errorMode == SkipAndCollect {
+			failed = append(failed, r.err.(*RecordError))
+			return
+		}
+		if firstErr == nil {
+			firstErr = r.err
+			stop()
+		}
+	}
+
+	// results arrive in any order, in ordered mode they wait until every earlier one is written
+	pending := make(map[int]result)
+	next := 0
+	for r := range results {
+		if !opts.Ordered {
+			handle(r)
+			continue
+		}
+
+		pending[r.index] = r
+		for p, ok := pending[next]; ok && firstErr == nil; p, ok = pending[next] {
+			delete(pending, next)
+			next++
+			if p.err == nil {
+				p.err = write(p.index, p.entry)
+			}
+			handle(p)
+		}
+	}
+
+	// the producer has to finish before its total and error can be read
+	stop()
+	<-produced
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if sourceErr != nil {
+		return sourceErr
+	}
+	if total == 0 {
+		return errors.New("no records to convert")
+	}
+	if len(failed) > 0 {
+		sort.Slice(failed, func(i, j int) bool { return failed[i].Index < failed[j].Index })
+		return failed
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package adapter_test
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lkendrickd/patterns/internal/patterns/adapter"
+)
+
+// orderedModern records the order the entries are written in
+type orderedModern struct {
+	mu     sync.Mutex
+	values []string
+}
+
+func (o *orderedModern) Entries() map[string]string { return nil }
+
+func (o *orderedModern) AddEntry(key, value string) error {
+	if value == "" {
+		return errors.New("value must not be empty")
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.values = append(o.values, value)
+	return nil
+}
+
+func TestConvertParallel(t *testing.T) {
+	records := make([]string, 200)
+	for i := range records {
+		records[i] = "record-" + strconv.Itoa(i)
+	}
+
+	tests := []struct {
+		name    string
+		opts    adapter.ParallelOptions
+		records []string
+		mode    adapter.ErrorMode
+		wantErr bool
+	}{
+		{"Unordered", adapter.ParallelOptions{Workers: 8}, records, adapter.FailFast, false},
+		{"Ordered", adapter.ParallelOptions{Workers: 8, Ordered: true}, records, adapter.FailFast, false},
+		{"SingleWorker", adapter.ParallelOptions{Workers: 1}, records, adapter.FailFast, false},
+		{"NoWorkers", adapter.ParallelOptions{}, records, adapter.FailFast, true},
+		{"NoRecords", adapter.ParallelOptions{Workers: 2}, nil, adapter.FailFast, true},
+		{"TransactionalUnsupported", adapter.ParallelOptions{Workers: 2}, records, adapter.Transactional, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modern := &orderedModern{}
+			adap := adapter.NewAdapter(&stubLegacy{records: tt.records}, modern, adapter.WithErrorMode(tt.mode))
+
+			err := adap.ConvertParallel(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConvertParallel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(modern.values) != len(tt.records) {
+				t.Fatalf("wrote %d entries, want %d", len(modern.values), len(tt.records))
+			}
+			if tt.opts.Ordered && !equalSlice(modern.values, tt.records) {
+				t.Errorf("ordered conversion wrote %v, want %v", modern.values, tt.records)
+			}
+		})
+	}
+}
+
+func TestConvertParallelEntriesAPI(t *testing.T) {
+	records := make([]string, 500)
+	for i := range records {
+		records[i] = "record-" + strconv.Itoa(i)
+	}
+
+	modern := adapter.NewEntriesAPI()
+	adap := adapter.NewAdapter(&stubLegacy{records: records}, modern)
+
+	if err := adap.ConvertParallel(adapter.ParallelOptions{Workers: 16}); err != nil {
+		t.Fatalf("ConvertParallel() error = %v", err)
+	}
+	if got := len(modern.Entries()); got != len(records) {
+		t.Errorf("got %d entries, want %d", got, len(records))
+	}
+}
+
+func TestConvertParallelErrors(t *testing.T) {
+	records := []string{"a", "b", "", "d", "", "f"}
+
+	tests := []struct {
+		name       string
+		mode       adapter.ErrorMode
+		ordered    bool
+		wantFailed []int
+		wantValues []string // only checked when ordered
+	}{
+		{"SkipAndCollectUnordered", adapter.SkipAndCollect, false, []int{2, 4}, nil},
+		{"SkipAndCollectOrdered", adapter.SkipAndCollect, true, []int{2, 4}, []string{"a", "b", "d", "f"}},
+		{"FailFastOrdered", adapter.FailFast, true, []int{2}, []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modern := &orderedModern{}
+			adap := adapter.NewAdapter(&stubLegacy{records: records}, modern, adapter.WithErrorMode(tt.mode))
+
+			err := adap.ConvertParallel(adapter.ParallelOptions{Workers: 3, Ordered: tt.ordered})
+
+			var failed []int
+			var collected adapter.ConversionErrors
+			var recordErr *adapter.RecordError
+			switch {
+			case errors.As(err, &collected):
+				for _, e := range collected {
+					failed = append(failed, e.Index)
+				}
+			case errors.As(err, &recordErr):
+				failed = append(failed, recordErr.Index)
+			default:
+				t.Fatalf("ConvertParallel() error = %v, want record errors", err)
+			}
+
+			if len(failed) != len(tt.wantFailed) {
+				t.Fatalf("failed = %v, want %v", failed, tt.wantFailed)
+			}
+			for i := range failed {
+				if failed[i] != tt.wantFailed[i] {
+					t.Errorf("failed = %v, want %v", failed, tt.wantFailed)
+				}
+			}
+			if tt.wantValues != nil && !equalSlice(modern.values, tt.wantValues) {
+				t.Errorf("wrote %v, want %v", modern.values, tt.wantValues)
+			}
+		})
+	}
+}
+
+func TestConvertParallelRateLimit(t *testing.T) {
+	modern := &orderedModern{}
+	adap := adapter.NewAdapter(&stubLegacy{records: []string{"a", "b", "c", "d", "e"}}, modern)
+
+	start := time.Now()
+	if err := adap.ConvertParallel(adapter.ParallelOptions{Workers: 5, RateLimit: 100}); err != nil {
+		t.Fatalf("ConvertParallel() error = %v", err)
+	}
+
+	// five writes at 100 per second take at least 50ms as every write waits for a tick
+	if elapsed := time.Since(start); elapsed < 45*time.Millisecond {
+		t.Errorf("5 writes at 100/s took %s, want at least 50ms", elapsed)
+	}
+}
+
+func TestConvertParallelRateLimitOutOfRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		rateLimit int
+	}{
+		{"Negative", -1},
+		{"AboveOnePerNanosecond", adapter.MaxRateLimit + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modern := &orderedModern{}
+			adap := adapter.NewAdapter(&stubLegacy{records: []string{"a"}}, modern)
+
+			err := adap.ConvertParallel(adapter.ParallelOptions{Workers: 1, RateLimit: tt.rateLimit})
+			if err == nil {
+				t.Fatalf("ConvertParallel() error = nil, want the rate limit rejected")
+			}
+			if len(modern.values) != 0 {
+				t.Errorf("ConvertParallel() wrote %v before rejecting the rate limit", modern.values)
+			}
+		})
+	}
+
+	// the highest rate limit still runs
+	modern := &orderedModern{}
+	adap := adapter.NewAdapter(&stubLegacy{records: []string{"a"}}, modern)
+	if err := adap.ConvertParallel(adapter.ParallelOptions{Workers: 1, RateLimit: adapter.MaxRateLimit}); err != nil {
+		t.Errorf("ConvertParallel() at MaxRateLimit error = %v", err)
+	}
+}
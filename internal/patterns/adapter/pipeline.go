@@ -0,0 +1,235 @@
+package adapter
+
+// The pipeline transforms the legacy records before they are converted. It is a chain of
+// named stages and every stage receives all the items the previous one kept, so a stage can
+// change a record, split one record into many, merge many into one or drop a record with a
+// reason. Every item remembers the legacy records it came from which lets the pipeline
+// report the before and after of each legacy record in a dry run.
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Item is a value moving through the pipeline
+type Item struct {
+	Value   string
+	Origins []int // Origins are the indexes of the legacy records the value came from
+}
+
+// Dropped is an item a stage removed from the pipeline
+type Dropped struct {
+	Item   Item
+	Stage  string // Stage is the name of the stage that dropped the item
+	Reason string
+}
+
+// Stage is a named step of the pipeline
+type Stage struct {
+	Name  string
+	Apply func(items []Item) (kept []Item, dropped []Dropped, err error)
+}
+
+// Pipeline is the chain of stages the records pass through in order
+type Pipeline []Stage
+
+// NewPipeline will return a new Pipeline running the stages in order
+func NewPipeline(stages ...Stage) Pipeline {
+	return Pipeline(stages)
+}
+
+// PipelineResult is the outcome of running the pipeline over the legacy records
+type PipelineResult struct {
+	Stages  []string // Stages are the names of the stages in order
+	Records []string // Records are the legacy records before the pipeline
+	Items   []Item   // Items are the values after the pipeline
+	Dropped []Dropped
+}
+
+// Values will return the values of the items after the pipeline
+func (r *PipelineResult) Values() []string {
+	values := make([]string, len(r.Items))
+	for i, item := range r.Items {
+		values[i] = item.Value
+	}
+	return values
+}
+
+// Run will pass the records through every stage of the pipeline. A stage error stops the
+// pipeline and is returned as a RecordError of the first legacy record of the failing item
+// holding that record as read, not the value the earlier stages made of it.
+func (p Pipeline) Run(records []string) (*PipelineResult, error) {
+	result := &PipelineResult{
+		Records: records,
+		Items:   make([]Item, len(records)),
+	}
+	for i, record := range records {
+		result.Items[i] = Item{Value: record, Origins: []int{i}}
+	}
+
+	for _, stage := range p {
+		result.Stages = append(result.Stages, stage.Name)
+
+		kept, dropped, err := stage.Apply(result.Items)
+		if err != nil {
+			var recordErr *RecordError
+			if errors.As(err, &recordErr) && recordErr.Index >= 0 && recordErr.Index < len(records) {
+				recordErr.Record = records[recordErr.Index]
+				recordErr.Err = fmt.Errorf("stage %s: %w", stage.Name, recordErr.Err)
+				return result, recordErr
+			}
+			return result, fmt.Errorf("stage %s: %w", stage.Name, err)
+		}
+		for i := range dropped {
+			dropped[i].Stage = stage.Name
+		}
+
+		result.Items = kept
+		result.Dropped = append(result.Dropped, dropped...)
+	}
+
+	return result, nil
+}
+
+// Report will render the before and after of every legacy record
+func (r *PipelineResult) Report() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "pipeline: %s\n", strings.Join(r.Stages, " -> "))
+
+	for index, record := range r.Records {
+		var after []string
+		for _, item := range r.Items {
+			if slices.Contains(item.Origins, index) {
+				after = append(after, fmt.Sprintf("%q", item.Value))
+			}
+		}
+
+		fmt.Fprintf(&b, "record %d %q", index, record)
+		if len(after) > 0 {
+			fmt.Fprintf(&b, " -> %s", strings.Join(after, ", "))
+		}
+		for _, drop := range r.Dropped {
+			if slices.Contains(drop.Item.Origins, index) {
+				fmt.Fprintf(&b, " dropped by %s: %s", drop.Stage, drop.Reason)
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "%d records in, %d out, %d dropped\n", len(r.Records), len(r.Items), len(r.Dropped))
+	return b.String()
+}
+
+/*##################################################################################
+# Stages
+##################################################################################*/
+
+// Map will return the stage replacing every value with fn(value), use it to normalize
+// or enrich the records
+func Map(name string, fn func(value string) string) Stage {
+	return Stage{
+		Name: name,
+		Apply: func(items []Item) ([]Item, []Dropped, error) {
+			kept := make([]Item, len(items))
+			for i, item := range items {
+				kept[i] = Item{Value: fn(item.Value), Origins: item.Origins}
+			}
+			return kept, nil, nil
+		},
+	}
+}
+
+// TrimSpace will return the stage removing the leading and trailing white space
+func TrimSpace() Stage {
+	return Map("trim", strings.TrimSpace)
+}
+
+// Validate will return the stage failing the pipeline on the first value fn rejects
+func Validate(name string, fn func(value string) error) Stage {
+	return Stage{
+		Name: name,
+		Apply: func(items []Item) ([]Item, []Dropped, error) {
+			for _, item := range items {
+				if err := fn(item.Value); err != nil {
+					return nil, nil, &RecordError{Index: item.Origins[0], Record: item.Value, Err: err}
+				}
+			}
+			return items, nil, nil
+		},
+	}
+}
+
+// DropIf will return the stage dropping the values fn returns true for with the reason
+func DropIf(name string, fn func(value string) (reason string, drop bool)) Stage {
+	return Stage{
+		Name: name,
+		Apply: func(items []Item) ([]Item, []Dropped, error) {
+			var (
+				kept    []Item
+				dropped []Dropped
+			)
+			for _, item := range items {
+				if reason, drop := fn(item.Value); drop {
+					dropped = append(dropped, Dropped{Item: item, Reason: reason})
+					continue
+				}
+				kept = append(kept, item)
+			}
+			return kept, dropped, nil
+		},
+	}
+}
+
+// Split will return the stage replacing every value with the values fn splits it into
+// each new value keeps the origin of the value it was split from
+func Split(name string, fn func(value string) []string) Stage {
+	return Stage{
+		Name: name,
+		Apply: func(items []Item) ([]Item, []Dropped, error) {
+			var kept []Item
+			for _, item := range items {
+				for _, part := range fn(item.Value) {
+					kept = append(kept, Item{Value: part, Origins: item.Origins})
+				}
+			}
+			return kept, nil, nil
+		},
+	}
+}
+
+// Merge will return the stage merging the values with the same group key into one value
+// using join. The merged value takes the place of the first value of its group and keeps
+// the origins of every merged value.
+func Merge(name string, key func(value string) string, join func(values []string) string) Stage {
+	return Stage{
+		Name: name,
+		Apply: func(items []Item) ([]Item, []Dropped, error) {
+			var order []string
+			groups := make(map[string][]Item)
+			for _, item := range items {
+				k := key(item.Value)
+				if _, ok := groups[k]; !ok {
+					order = append(order, k)
+				}
+				groups[k] = append(groups[k], item)
+			}
+
+			kept := make([]Item, 0, len(order))
+			for _, k := range order {
+				var (
+					values  []string
+					origins []int
+				)
+				for _, item := range groups[k] {
+					values = append(values, item.Value)
+					origins = append(origins, item.Origins...)
+				}
+				kept = append(kept, Item{Value: join(values), Origins: origins})
+			}
+			return kept, nil, nil
+		},
+	}
+}
@@ -0,0 +1,219 @@
+package adapter_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/patterns/adapter"
+)
+
+// dropEmpty drops the empty values
+var dropEmpty = adapter.DropIf("drop-empty", func(value string) (string, bool) {
+	return "empty record", value == ""
+})
+
+func TestPipelineRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		pipeline    adapter.Pipeline
+		records     []string
+		wantValues  []string
+		wantDropped int
+		wantErr     bool
+	}{
+		{
+			name:       "EmptyPipelineIsIdentity",
+			pipeline:   adapter.NewPipeline(),
+			records:    []string{"foo", "bar"},
+			wantValues: []string{"foo", "bar"},
+		},
+		{
+			name:       "TrimAndNormalize",
+			pipeline:   adapter.NewPipeline(adapter.TrimSpace(), adapter.Map("lower", strings.ToLower)),
+			records:    []string{"  Foo ", "BAR"},
+			wantValues: []string{"foo", "bar"},
+		},
+		{
+			name:       "Enrich",
+			pipeline:   adapter.NewPipeline(adapter.Map("prefix", func(v string) string { return "legacy:" + v })),
+			records:    []string{"foo"},
+			wantValues: []string{"legacy:foo"},
+		},
+		{
+			name:        "DropWithReason",
+			pipeline:    adapter.NewPipeline(adapter.TrimSpace(), dropEmpty),
+			records:     []string{"foo", "  ", "bar"},
+			wantValues:  []string{"foo", "bar"},
+			wantDropped: 1,
+		},
+		{
+			name: "SplitOneIntoMany",
+			pipeline: adapter.NewPipeline(adapter.Split("split", func(v string) []string {
+				return strings.Split(v, ";")
+			})),
+			records:    []string{"a;b", "c"},
+			wantValues: []string{"a", "b", "c"},
+		},
+		{
+			name: "MergeManyIntoOne",
+			pipeline: adapter.NewPipeline(adapter.Merge("merge",
+				func(v string) string { return v[:1] },
+				func(vs []string) string { return strings.Join(vs, "+") },
+			)),
+			records:    []string{"foo", "bar", "fizz", "baz"},
+			wantValues: []string{"foo+fizz", "bar+baz"},
+		},
+		{
+			name: "ValidateFails",
+			pipeline: adapter.NewPipeline(adapter.Validate("no-digits", func(v string) error {
+				if strings.ContainsAny(v, "0123456789") {
+					return errors.New("digits are not allowed")
+				}
+				return nil
+			})),
+			records: []string{"foo", "b4r"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.pipeline.Run(tt.records)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				var recordErr *adapter.RecordError
+				if !errors.As(err, &recordErr) || recordErr.Index != 1 {
+					t.Errorf("Run() error = %v, want a RecordError for record 1", err)
+				}
+				return
+			}
+
+			if got := result.Values(); !equalSlice(got, tt.wantValues) {
+				t.Errorf("Values() = %q, want %q", got, tt.wantValues)
+			}
+			if len(result.Dropped) != tt.wantDropped {
+				t.Errorf("Dropped = %v, want %d", result.Dropped, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestPipelineReport(t *testing.T) {
+	pipeline := adapter.NewPipeline(
+		adapter.TrimSpace(),
+		dropEmpty,
+		adapter.Split("split", func(v string) []string { return strings.Split(v, ";") }),
+	)
+
+	result, err := pipeline.Run([]string{" foo ", "", "a;b"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := `pipeline: trim -> drop-empty -> split
+record 0 " foo " -> "foo"
+record 1 "" dropped by drop-empty: empty record
+record 2 "a;b" -> "a", "b"
+3 records in, 3 out, 1 dropped
+`
+	if got := result.Report(); got != want {
+		t.Errorf("Report() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRecordsAdapterPipeline(t *testing.T) {
+	legacy := &stubLegacy{records: []string{" foo ", "", "bar;baz"}}
+	modern := adapter.NewEntriesAPI()
+	adap := adapter.NewAdapter(legacy, modern,
+		adapter.WithPipeline(adapter.NewPipeline(
+			adapter.TrimSpace(),
+			dropEmpty,
+			adapter.Split("split", func(v string) []string { return strings.Split(v, ";") }),
+		)),
+	)
+
+	// the dry run reports without writing
+	result, err := adap.DryRunPipeline()
+	if err != nil {
+		t.Fatalf("DryRunPipeline() error = %v", err)
+	}
+	if len(result.Items) != 3 || len(modern.Entries()) != 0 {
+		t.Fatalf("DryRunPipeline() items = %v entries = %v", result.Items, modern.Entries())
+	}
+
+	// the empty record that AddEntry rejects is dropped before it reaches the modern API
+	if err := adap.ConvertRecords(); err != nil {
+		t.Fatalf("ConvertRecords() error = %v", err)
+	}
+	if got := sortedValues(modern.Entries()); !equalSlice(got, []string{"bar", "baz", "foo"}) {
+		t.Errorf("Entries() = %v", got)
+	}
+}
+
+// TestRecordErrorIndexAfterPipeline checks a failing record is reported at its legacy index
+// even when a pipeline stage such as Split changed the number of values
+func TestRecordErrorIndexAfterPipeline(t *testing.T) {
+	tests := []struct {
+		name    string
+		convert func(adap *adapter.RecordsAdapter) error
+	}{
+		{"ConvertRecords", func(adap *adapter.RecordsAdapter) error {
+			return adap.ConvertRecords()
+		}},
+		{"ConvertStream", func(adap *adapter.RecordsAdapter) error {
+			_, err := adap.ConvertStream(2, nil)
+			return err
+		}},
+		{"ConvertParallel", func(adap *adapter.RecordsAdapter) error {
+			return adap.ConvertParallel(adapter.ParallelOptions{Workers: 2, Ordered: true})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			legacy := &stubLegacy{records: []string{"a,b", "c", ""}}
+			adap := adapter.NewAdapter(legacy, adapter.NewEntriesAPI(),
+				adapter.WithErrorMode(adapter.SkipAndCollect),
+				adapter.WithPipeline(adapter.NewPipeline(
+					adapter.Split("split", func(v string) []string { return strings.Split(v, ",") }),
+				)),
+			)
+
+			var recordErr *adapter.RecordError
+			if err := tt.convert(adap); !errors.As(err, &recordErr) {
+				t.Fatalf("error = %v, want a RecordError", err)
+			}
+			if recordErr.Index != 2 || recordErr.Record != "" {
+				t.Errorf("RecordError = %d %q, want the empty legacy record 2", recordErr.Index, recordErr.Record)
+			}
+		})
+	}
+}
+
+func TestValidateReportsLegacyRecord(t *testing.T) {
+	errBad := errors.New("bad value")
+	pipeline := adapter.NewPipeline(
+		adapter.TrimSpace(),
+		adapter.Validate("not-bad", func(v string) error {
+			if v == "bad" {
+				return errBad
+			}
+			return nil
+		}),
+	)
+
+	_, err := pipeline.Run([]string{"good", "  bad  "})
+	var recordErr *adapter.RecordError
+	if !errors.As(err, &recordErr) {
+		t.Fatalf("Run() error = %v, want a RecordError", err)
+	}
+	if recordErr.Index != 1 || recordErr.Record != "  bad  " {
+		t.Errorf("RecordError = %d %q, want the untouched legacy record 1 %q", recordErr.Index, recordErr.Record, "  bad  ")
+	}
+	if !errors.Is(err, errBad) || recordErr.Error() != `record 1 "  bad  ": stage not-bad: bad value` {
+		t.Errorf("Error() = %q", recordErr.Error())
+	}
+}
@@ -0,0 +1,317 @@
+package adapter
+
+// The schema mapping converts structured legacy rows into typed modern structs. A legacy row
+// is a list of fields in the order of the legacy columns and the schema declares how each
+// field of the modern struct is filled: which column it comes from under which name, the type
+// the text is cast to, a default for empty values and computed fields derived from the fields
+// mapped before them. A row that does not fit fails with a FieldError naming the row and field.
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Row is a structured legacy record, the fields are in the order of the legacy columns
+type Row []string
+
+// FieldType is the type the text of a legacy field is cast to
+type FieldType string
+
+const (
+	// TypeString keeps the text as it is
+	TypeString FieldType = "string"
+	// TypeInt casts the text to an integer
+	TypeInt FieldType = "int"
+	// TypeFloat casts the text to a floating point number
+	TypeFloat FieldType = "float"
+	// TypeBool casts the text to a boolean accepting the forms of strconv.ParseBool
+	TypeBool FieldType = "bool"
+)
+
+// Field declares how one field of the modern struct is filled
+type Field struct {
+	Name     string    // Name is the field of the struct, its adapter tag or its Go name
+	From     string    // From is the legacy column the field is read from, it defaults to Name
+	Type     FieldType // Type is the type the legacy text is cast to, it defaults to TypeString
+	Default  string    // Default replaces an empty legacy value before it is cast
+	Required bool      // Required fails the row when the value and the default are empty
+	// Compute derives the value from the fields mapped before it instead of reading a column
+	Compute func(values map[string]any) (any, error)
+	// Validate checks the value after it was cast or computed
+	Validate func(value any) error
+}
+
+// FieldError is the error of a legacy row field that could not be mapped
+type FieldError struct {
+	Row   int    // Row is the position of the row in the legacy rows
+	Field string // Field is the name of the modern field
+	Value string // Value is the legacy text of the field, empty for computed fields
+	Err   error  // Err is the reason the field failed
+}
+
+// Error will return the error message naming the row and field
+func (e *FieldError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("row %d field %s: %v", e.Row, e.Field, e.Err)
+	}
+	return fmt.Sprintf("row %d field %s %q: %v", e.Row, e.Field, e.Value, e.Err)
+}
+
+// Unwrap will return the reason the field failed
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Schema is the declarative mapping of legacy rows to the struct T
+type Schema[T any] struct {
+	columns map[string]int // columns are the positions of the legacy columns by name
+	fields  []Field
+	targets []int // targets are the struct field indexes of the fields in order
+}
+
+// NewSchema will return a new Schema mapping the rows with the legacy columns into T. The
+// mapping is checked up front so a field naming an unknown column or struct field, or a
+// type that does not fit the struct field fails here rather than on the first row.
+func NewSchema[T any](columns []string, fields ...Field) (*Schema[T], error) {
+	structType := reflect.TypeOf((*T)(nil)).Elem()
+	if structType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schema target %s must be a struct", structType)
+	}
+
+	s := &Schema[T]{
+		columns: make(map[string]int, len(columns)),
+		fields:  make([]Field, len(fields)),
+		targets: make([]int, len(fields)),
+	}
+	for i, column := range columns {
+		s.columns[column] = i
+	}
+
+	for i, field := range fields {
+		if field.Type == "" {
+			field.Type = TypeString
+		}
+		if field.From == "" && field.Compute == nil {
+			field.From = field.Name
+		}
+
+		target, ok := structField(structType, field.Name)
+		if !ok {
+			return nil, fmt.Errorf("field %s: %s has no such field", field.Name, structType)
+		}
+		if field.Compute == nil {
+			if _, ok := s.columns[field.From]; !ok {
+				return nil, fmt.Errorf("field %s: unknown legacy column %q", field.Name, field.From)
+			}
+			if !fits(field.Type, structType.Field(target).Type.Kind()) {
+				return nil, fmt.Errorf("field %s: type %s does not fit %s", field.Name, field.Type, structType.Field(target).Type)
+			}
+		}
+
+		s.fields[i] = field
+		s.targets[i] = target
+	}
+
+	return s, nil
+}
+
+// Map will map the legacy row at the index into a T or return the FieldError of the first
+// field that failed
+func (s *Schema[T]) Map(index int, row Row) (T, error) {
+	var target T
+	structValue := reflect.ValueOf(&target).Elem()
+	values := make(map[string]any, len(s.fields))
+
+	for i, field := range s.fields {
+		var (
+			text  string
+			value any
+			err   error
+		)
+
+		if field.Compute != nil {
+			value, err = field.Compute(values)
+		} else {
+			if column := s.columns[field.From]; column < len(row) {
+				text = row[column]
+			}
+			value, err = castField(field, text)
+		}
+		if err == nil && field.Validate != nil {
+			err = field.Validate(value)
+		}
+		if err == nil {
+			err = setField(structValue.Field(s.targets[i]), value)
+		}
+		if err != nil {
+			return target, &FieldError{Row: index, Field: field.Name, Value: text, Err: err}
+		}
+
+		values[field.Name] = value
+	}
+
+	return target, nil
+}
+
+// Converter will return the Converter mapping the rows of a Source in order, each call
+// returns a Converter numbering the rows from 0 again
+func (s *Schema[T]) Converter() Converter[Row, T] {
+	index := 0
+	return func(row Row) (T, error) {
+		defer func() { index++ }()
+		return s.Map(index, row)
+	}
+}
+
+// StructStore is the modern API storing the converted structs by key
+type StructStore[T any] struct {
+	key     func(T) string
+	entries map[string]T
+}
+
+// NewStructStore will return a new StructStore keying every struct with the key function
+func NewStructStore[T any](key func(T) string) *StructStore[T] {
+	return &StructStore[T]{
+		key:     key,
+		entries: make(map[string]T),
+	}
+}
+
+// Add will store the struct under its key and is the Sink of a structured Adapter
+func (s *StructStore[T]) Add(value T) error {
+	key := s.key(value)
+	if key == "" {
+		return fmt.Errorf("key must not be empty")
+	}
+	if _, ok := s.entries[key]; ok {
+		return fmt.Errorf("entry %q already exists", key)
+	}
+
+	s.entries[key] = value
+
+	return nil
+}
+
+// Get will return the struct stored under the key
+func (s *StructStore[T]) Get(key string) (T, bool) {
+	value, ok := s.entries[key]
+	return value, ok
+}
+
+// Keys will return the keys of the stored structs in order
+func (s *StructStore[T]) Keys() []string {
+	keys := make([]string, 0, len(s.entries))
+	for key := range s.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// NewStructuredAdapter will return the generic Adapter mapping the legacy rows with the
+// schema into the store
+func NewStructuredAdapter[T any](rows []Row, schema *Schema[T], store *StructStore[T]) *Adapter[Row, T] {
+	return New(SliceSource(rows), schema.Converter(), store.Add)
+}
+
+/*##################################################################################
+# Helper Functions
+##################################################################################*/
+
+// structField will return the index of the struct field with the adapter tag or Go name
+func structField(structType reflect.Type, name string) (int, bool) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if tag, ok := field.Tag.Lookup("adapter"); ok && tag == name {
+			return i, true
+		}
+	}
+	for i := 0; i < structType.NumField(); i++ {
+		if field := structType.Field(i); field.IsExported() && field.Name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// fits will report whether a field type can be stored in a struct field of the kind
+func fits(fieldType FieldType, kind reflect.Kind) bool {
+	switch fieldType {
+	case TypeString:
+		return kind == reflect.String
+	case TypeInt:
+		return kind >= reflect.Int && kind <= reflect.Int64
+	case TypeFloat:
+		return kind == reflect.Float32 || kind == reflect.Float64
+	case TypeBool:
+		return kind == reflect.Bool
+	default:
+		return false
+	}
+}
+
+// castField will cast the legacy text of the field to its type applying the default
+func castField(field Field, text string) (any, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		text = field.Default
+	}
+	if text == "" {
+		if field.Required {
+			return nil, fmt.Errorf("is required")
+		}
+		return nil, nil
+	}
+
+	switch field.Type {
+	case TypeInt:
+		value, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("is not an int")
+		}
+		return value, nil
+	case TypeFloat:
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("is not a float")
+		}
+		return value, nil
+	case TypeBool:
+		value, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("is not a bool")
+		}
+		return value, nil
+	default:
+		return text, nil
+	}
+}
+
+// setField will store the value in the struct field, a nil value leaves the zero value
+func setField(field reflect.Value, value any) error {
+	if value == nil {
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Type().AssignableTo(field.Type()):
+		field.Set(v)
+	case v.CanInt() && field.CanInt():
+		if field.OverflowInt(v.Int()) {
+			return fmt.Errorf("%d overflows %s", v.Int(), field.Type())
+		}
+		field.SetInt(v.Int())
+	case v.CanFloat() && field.CanFloat():
+		field.SetFloat(v.Float())
+	default:
+		return fmt.Errorf("%T can not be stored in %s", value, field.Type())
+	}
+	return nil
+}
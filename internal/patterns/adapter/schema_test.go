@@ -0,0 +1,196 @@
+package adapter_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/patterns/adapter"
+)
+
+// account is the typed modern record of the schema tests
+type account struct {
+	ID      int64   `adapter:"id"`
+	Owner   string  `adapter:"owner"`
+	Balance float64 `adapter:"balance"`
+	Active  bool
+	Tier    string `adapter:"tier"`
+	Small   int8   `adapter:"small"`
+}
+
+var accountColumns = []string{"account_id", "holder", "balance", "active", "small"}
+
+func accountSchema(t *testing.T, extra ...adapter.Field) *adapter.Schema[account] {
+	t.Helper()
+
+	fields := append([]adapter.Field{
+		{Name: "id", From: "account_id", Type: adapter.TypeInt, Required: true},
+		{Name: "owner", From: "holder", Required: true},
+		{Name: "balance", Type: adapter.TypeFloat, Default: "0"},
+		{Name: "Active", From: "active", Type: adapter.TypeBool, Default: "true"},
+		{Name: "tier", Compute: func(values map[string]any) (any, error) {
+			if values["balance"].(float64) >= 1000 {
+				return "gold", nil
+			}
+			return "standard", nil
+		}},
+	}, extra...)
+
+	schema, err := adapter.NewSchema[account](accountColumns, fields...)
+	if err != nil {
+		t.Fatalf("NewSchema() error = %v", err)
+	}
+	return schema
+}
+
+func TestSchemaMap(t *testing.T) {
+	tests := []struct {
+		name      string
+		extra     []adapter.Field
+		row       adapter.Row
+		want      account
+		wantField string // wantField is the field the FieldError names, empty for no error
+		wantErr   string
+	}{
+		{
+			name: "RenamesAndCasts",
+			row:  adapter.Row{"7", "ada", "1500.5", "false"},
+			want: account{ID: 7, Owner: "ada", Balance: 1500.5, Active: false, Tier: "gold"},
+		},
+		{
+			name: "AppliesDefaults",
+			row:  adapter.Row{"8", "alan", " ", ""},
+			want: account{ID: 8, Owner: "alan", Balance: 0, Active: true, Tier: "standard"},
+		},
+		{
+			name: "ShortRowUsesDefaults",
+			row:  adapter.Row{"9", "grace"},
+			want: account{ID: 9, Owner: "grace", Active: true, Tier: "standard"},
+		},
+		{
+			name:      "RequiredFieldMissing",
+			row:       adapter.Row{"10", "", "1", "true"},
+			wantField: "owner",
+			wantErr:   "is required",
+		},
+		{
+			name:      "CastFails",
+			row:       adapter.Row{"ten", "ada", "1", "true"},
+			wantField: "id",
+			wantErr:   `row 3 field id "ten": is not an int`,
+		},
+		{
+			name: "ValidateFails",
+			extra: []adapter.Field{{Name: "small", Type: adapter.TypeInt, Validate: func(value any) error {
+				if value.(int64) < 0 {
+					return errors.New("must not be negative")
+				}
+				return nil
+			}}},
+			row:       adapter.Row{"11", "ada", "1", "true", "-1"},
+			wantField: "small",
+			wantErr:   "must not be negative",
+		},
+		{
+			name:      "OverflowFails",
+			extra:     []adapter.Field{{Name: "small", Type: adapter.TypeInt}},
+			row:       adapter.Row{"12", "ada", "1", "true", "300"},
+			wantField: "small",
+			wantErr:   "overflows int8",
+		},
+		{
+			name: "ComputeFails",
+			extra: []adapter.Field{{Name: "small", Compute: func(map[string]any) (any, error) {
+				return nil, errors.New("no value")
+			}}},
+			row:       adapter.Row{"13", "ada", "1", "true"},
+			wantField: "small",
+			wantErr:   "row 3 field small: no value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := accountSchema(t, tt.extra...).Map(3, tt.row)
+
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("Map() error = %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("Map() got %+v, want %+v", got, tt.want)
+				}
+				return
+			}
+
+			var fieldErr *adapter.FieldError
+			if !errors.As(err, &fieldErr) {
+				t.Fatalf("Map() error = %v, want a FieldError", err)
+			}
+			if fieldErr.Row != 3 || fieldErr.Field != tt.wantField {
+				t.Errorf("FieldError names row %d field %s, want row 3 field %s", fieldErr.Row, fieldErr.Field, tt.wantField)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Map() error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewSchemaRejectsBadMappings(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  []adapter.Field
+		wantErr string
+	}{
+		{"UnknownStructField", []adapter.Field{{Name: "missing", From: "holder"}}, "has no such field"},
+		{"UnknownColumn", []adapter.Field{{Name: "owner", From: "name"}}, `unknown legacy column "name"`},
+		{"TypeMismatch", []adapter.Field{{Name: "owner", From: "holder", Type: adapter.TypeInt}}, "type int does not fit string"},
+		{"UnknownType", []adapter.Field{{Name: "owner", From: "holder", Type: "date"}}, "type date does not fit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := adapter.NewSchema[account](accountColumns, tt.fields...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewSchema() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := adapter.NewSchema[string](nil); err == nil {
+		t.Error("NewSchema() of a non struct got no error")
+	}
+}
+
+func TestStructuredAdapter(t *testing.T) {
+	schema := accountSchema(t)
+	store := adapter.NewStructStore(func(a account) string { return fmt.Sprintf("acct-%d", a.ID) })
+
+	rows := []adapter.Row{
+		{"1", "ada", "10", "true"},
+		{"2", "alan", "2000", "false"},
+		{"3", "grace", "oops", "true"},
+	}
+
+	converted, err := adapter.NewStructuredAdapter(rows, schema, store).Convert()
+
+	var fieldErr *adapter.FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Row != 2 || fieldErr.Field != "balance" {
+		t.Fatalf("Convert() error = %v, want the FieldError of row 2 field balance", err)
+	}
+	if converted != 2 {
+		t.Errorf("Convert() converted = %d, want 2", converted)
+	}
+	if !equalSlice(store.Keys(), []string{"acct-1", "acct-2"}) {
+		t.Errorf("Keys() got %v, want [acct-1 acct-2]", store.Keys())
+	}
+	if got, _ := store.Get("acct-2"); got.Tier != "gold" || got.Active {
+		t.Errorf("Get(acct-2) got %+v, want an inactive gold account", got)
+	}
+
+	if err := store.Add(account{ID: 1}); err == nil {
+		t.Error("Add() of an existing key got no error")
+	}
+}
@@ -0,0 +1,363 @@
+package adapter
+
+// The persistent modern APIs keep the entries across runs. The JSON file store rewrites a
+// single JSON document on every write and swaps it in with a rename so a crash never leaves
+// a half written file. The log store appends every write to a log and replays it on open,
+// a write torn by a crash is the incomplete last line of the log which the replay cuts off.
+// Compaction rewrites the log with only the live entries the same atomic way.
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// JSONFileStore is the struct that persists the entries as a JSON file and implements
+// the ModernAPI and EntryRemover interfaces
+type JSONFileStore struct {
+	mu      sync.RWMutex
+	path    string
+	entries map[string]string
+}
+
+// OpenJSONFileStore will return a new JSONFileStore loading the entries of the file
+// a missing file is an empty store that is created on the first write
+func OpenJSONFileStore(path string) (*JSONFileStore, error) {
+	s := &JSONFileStore{
+		path:    path,
+		entries: make(map[string]string),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		return nil, errors.Wrapf(err, "could not load %s", path)
+	}
+	return s, nil
+}
+
+// Entries will return a copy of the entries and implements the ModernAPI interface
+func (s *JSONFileStore) Entries() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyMap(s.entries)
+}
+
+// AddEntry will add the entry and write the file and implements the ModernAPI interface
+func (s *JSONFileStore) AddEntry(key string, value string) error {
+	if err := validateEntry(key, value); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.entries[key]
+	s.entries[key] = value
+
+	// keep memory and file in step when the write fails
+	if err := s.save(); err != nil {
+		if existed {
+			s.entries[key] = previous
+		} else {
+			delete(s.entries, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// RemoveEntry will remove the entry and write the file and implements the EntryRemover interface
+func (s *JSONFileStore) RemoveEntry(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.entries[key]
+	if !ok {
+		return fmt.Errorf("entry %q does not exist", key)
+	}
+	delete(s.entries, key)
+
+	if err := s.save(); err != nil {
+		s.entries[key] = previous
+		return err
+	}
+	return nil
+}
+
+// save will write the entries to the file atomically
+func (s *JSONFileStore) save() error {
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// logRecord is a single write of the log store
+type logRecord struct {
+	Op    string `json:"op"` // Op is either put or delete
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+}
+
+// LogStore is the struct that persists the entries as an append only log of writes and
+// implements the ModernAPI and EntryRemover interfaces
+type LogStore struct {
+	mu      sync.RWMutex
+	path    string
+	file    *os.File
+	entries map[string]string
+	records int   // records are the writes in the log, more than the entries once overwritten
+	size    int64 // size is the length of the complete records in the log
+}
+
+// OpenLogStore will return a new LogStore replaying the log of the file
+// a missing file is an empty store, an incomplete last record left by a crash is removed
+func OpenLogStore(path string) (*LogStore, error) {
+	s := &LogStore{
+		path:    path,
+		entries: make(map[string]string),
+	}
+
+	if err := s.replay(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s.file = file
+	return s, nil
+}
+
+// Entries will return a copy of the entries and implements the ModernAPI interface
+func (s *LogStore) Entries() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyMap(s.entries)
+}
+
+// AddEntry will append a put to the log and implements the ModernAPI interface
+func (s *LogStore) AddEntry(key string, value string) error {
+	if err := validateEntry(key, value); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.append(logRecord{Op: "put", Key: key, Value: value}); err != nil {
+		return err
+	}
+	s.entries[key] = value
+	return nil
+}
+
+// RemoveEntry will append a delete to the log and implements the EntryRemover interface
+func (s *LogStore) RemoveEntry(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[key]; !ok {
+		return fmt.Errorf("entry %q does not exist", key)
+	}
+	if err := s.append(logRecord{Op: "delete", Key: key}); err != nil {
+		return err
+	}
+	delete(s.entries, key)
+	return nil
+}
+
+// Len will return the number of writes in the log
+func (s *LogStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.records
+}
+
+// Compact will rewrite the log with a single put per live entry dropping the overwritten
+// and deleted ones. The new log replaces the old one atomically.
+func (s *LogStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.entries))
+	for key := range s.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var data []byte
+	for _, key := range keys {
+		line, err := json.Marshal(logRecord{Op: "put", Key: key, Value: s.entries[key]})
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	tmp, err := writeTemp(s.path, data)
+	if err != nil {
+		return err
+	}
+
+	// open the new log before it replaces the old one so a failure leaves the store
+	// appending to the old log which is still in place
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	s.file.Close()
+	s.file = file
+	s.records = len(keys)
+	s.size = int64(len(data))
+	return nil
+}
+
+// Close will close the log file
+func (s *LogStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// append will write the record to the end of the log and sync it to disk, a failed write
+// is cut off again so the next record does not land behind a partial one
+func (s *LogStore) append(record logRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	_, err = s.file.Write(line)
+	if err == nil {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		if truncErr := s.file.Truncate(s.size); truncErr != nil {
+			return errors.Wrapf(err, "could not remove the partial record: %v", truncErr)
+		}
+		return err
+	}
+
+	s.records++
+	s.size += int64(len(line))
+	return nil
+}
+
+// replay will apply every write of the log to the entries. Every record ends with a newline
+// so a last line without one is a write torn by a crash, it never completed and is cut off.
+func (s *LogStore) replay() error {
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(data) > 0 {
+				return s.truncateTorn(line)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var record logRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return errors.Wrapf(err, "could not replay %s line %d", s.path, line)
+		}
+
+		switch record.Op {
+		case "put":
+			s.entries[record.Key] = record.Value
+		case "delete":
+			delete(s.entries, record.Key)
+		default:
+			return fmt.Errorf("could not replay %s line %d: unknown op %q", s.path, line, record.Op)
+		}
+		s.records++
+		s.size += int64(len(data))
+	}
+}
+
+// truncateTorn will cut the torn record on the line off the end of the log
+func (s *LogStore) truncateTorn(line int) error {
+	if err := os.Truncate(s.path, s.size); err != nil {
+		return errors.Wrapf(err, "could not remove the torn record on %s line %d", s.path, line)
+	}
+	return nil
+}
+
+/*##################################################################################
+# Helper Functions
+##################################################################################*/
+
+// writeFileAtomic will write the data to a temporary file next to the path and rename it
+// over the path so readers see either the old or the new file and never a partial one
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := writeTemp(path, data)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// writeTemp will write the data to a temporary file next to the path synced to disk and
+// return its name, the file is removed again when the write fails
+func writeTemp(path string, data []byte) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(0o644)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
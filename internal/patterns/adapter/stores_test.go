@@ -0,0 +1,280 @@
+package adapter_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/patterns/adapter"
+)
+
+// backend opens a ModernAPI, reopen returns a new instance over the same storage
+// or nil when the backend does not persist
+type backend struct {
+	name string
+	open func(t *testing.T) (api adapter.ModernAPI, reopen func() adapter.ModernAPI)
+}
+
+// backends are every ModernAPI implementation run through the shared suite
+var backends = []backend{
+	{
+		name: "EntriesAPI",
+		open: func(t *testing.T) (adapter.ModernAPI, func() adapter.ModernAPI) {
+			return adapter.NewEntriesAPI(), nil
+		},
+	},
+	{
+		name: "JSONFileStore",
+		open: func(t *testing.T) (adapter.ModernAPI, func() adapter.ModernAPI) {
+			path := filepath.Join(t.TempDir(), "entries.json")
+			open := func() adapter.ModernAPI {
+				store, err := adapter.OpenJSONFileStore(path)
+				if err != nil {
+					t.Fatalf("OpenJSONFileStore() error = %v", err)
+				}
+				return store
+			}
+			return open(), open
+		},
+	},
+	{
+		name: "LogStore",
+		open: func(t *testing.T) (adapter.ModernAPI, func() adapter.ModernAPI) {
+			path := filepath.Join(t.TempDir(), "entries.log")
+			open := func() adapter.ModernAPI {
+				store, err := adapter.OpenLogStore(path)
+				if err != nil {
+					t.Fatalf("OpenLogStore() error = %v", err)
+				}
+				t.Cleanup(func() { store.Close() })
+				return store
+			}
+			return open(), open
+		},
+	},
+}
+
+// TestModernAPISuite runs the shared interface suite every ModernAPI backend must pass
+func TestModernAPISuite(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			modernAPISuite(t, b)
+		})
+	}
+}
+
+func modernAPISuite(t *testing.T, b backend) {
+	t.Run("AddAndList", func(t *testing.T) {
+		api, _ := b.open(t)
+		if err := api.AddEntry("k1", "v1"); err != nil {
+			t.Fatalf("AddEntry() error = %v", err)
+		}
+		if !equalMap(api.Entries(), map[string]string{"k1": "v1"}) {
+			t.Errorf("Entries() = %v", api.Entries())
+		}
+	})
+
+	t.Run("RejectsEmpty", func(t *testing.T) {
+		api, _ := b.open(t)
+		if err := api.AddEntry("", "v1"); err == nil {
+			t.Errorf("AddEntry() with empty key error = nil")
+		}
+		if err := api.AddEntry("k1", ""); err == nil {
+			t.Errorf("AddEntry() with empty value error = nil")
+		}
+		if len(api.Entries()) != 0 {
+			t.Errorf("Entries() = %v, want empty", api.Entries())
+		}
+	})
+
+	t.Run("Overwrite", func(t *testing.T) {
+		api, _ := b.open(t)
+		api.AddEntry("k1", "v1")
+		api.AddEntry("k1", "v2")
+		if !equalMap(api.Entries(), map[string]string{"k1": "v2"}) {
+			t.Errorf("Entries() = %v", api.Entries())
+		}
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		api, _ := b.open(t)
+		remover, ok := api.(adapter.EntryRemover)
+		if !ok {
+			t.Skip("backend can not remove entries")
+		}
+		api.AddEntry("k1", "v1")
+		api.AddEntry("k2", "v2")
+		if err := remover.RemoveEntry("k1"); err != nil {
+			t.Fatalf("RemoveEntry() error = %v", err)
+		}
+		if err := remover.RemoveEntry("k1"); err == nil {
+			t.Errorf("RemoveEntry() of a missing key error = nil")
+		}
+		if !equalMap(api.Entries(), map[string]string{"k2": "v2"}) {
+			t.Errorf("Entries() = %v", api.Entries())
+		}
+	})
+
+	t.Run("Adapter", func(t *testing.T) {
+		api, _ := b.open(t)
+		adap := adapter.NewAdapter(adapter.NewRecordsAPI(), api, adapter.WithKeyStrategy(adapter.ContentHashKeys()))
+		if err := adap.ConvertRecords(); err != nil {
+			t.Fatalf("ConvertRecords() error = %v", err)
+		}
+		if got := sortedValues(api.Entries()); !equalSlice(got, []string{"bar", "baz", "foo"}) {
+			t.Errorf("Entries() = %v", got)
+		}
+	})
+
+	t.Run("Persists", func(t *testing.T) {
+		api, reopen := b.open(t)
+		if reopen == nil {
+			t.Skip("backend does not persist")
+		}
+		api.AddEntry("k1", "v1")
+		api.AddEntry("k2", "v2")
+		api.AddEntry("k1", "v3")
+		if remover, ok := api.(adapter.EntryRemover); ok {
+			remover.RemoveEntry("k2")
+		}
+
+		if got := reopen().Entries(); !equalMap(got, map[string]string{"k1": "v3"}) {
+			t.Errorf("Entries() after reopen = %v", got)
+		}
+	})
+
+	t.Run("EntriesIsACopy", func(t *testing.T) {
+		api, _ := b.open(t)
+		api.AddEntry("k1", "v1")
+		api.Entries()["k1"] = "changed"
+		if api.Entries()["k1"] != "v1" {
+			t.Errorf("Entries() mutation leaked into the store")
+		}
+	})
+}
+
+func TestJSONFileStoreAtomicWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "entries.json")
+
+	store, err := adapter.OpenJSONFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenJSONFileStore() error = %v", err)
+	}
+	store.AddEntry("k1", "v1")
+
+	// only the store file is left behind, the temporary file was renamed over it
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 || files[0].Name() != "entries.json" {
+		t.Errorf("files in store dir = %v, want only entries.json", files)
+	}
+
+	os.WriteFile(path, []byte("not json"), 0o644)
+	if _, err := adapter.OpenJSONFileStore(path); err == nil {
+		t.Errorf("OpenJSONFileStore() of a corrupt file error = nil")
+	}
+}
+
+func TestLogStoreCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entries.log")
+
+	store, err := adapter.OpenLogStore(path)
+	if err != nil {
+		t.Fatalf("OpenLogStore() error = %v", err)
+	}
+	defer store.Close()
+
+	for _, value := range []string{"v1", "v2", "v3"} {
+		store.AddEntry("k1", value)
+	}
+	store.AddEntry("k2", "v1")
+	store.RemoveEntry("k2")
+
+	if store.Len() != 5 {
+		t.Fatalf("Len() before compaction = %d, want 5", store.Len())
+	}
+	if err := store.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if store.Len() != 1 {
+		t.Errorf("Len() after compaction = %d, want 1", store.Len())
+	}
+
+	// writes after the compaction go to the new log
+	store.AddEntry("k3", "v1")
+
+	reopened, err := adapter.OpenLogStore(path)
+	if err != nil {
+		t.Fatalf("OpenLogStore() error = %v", err)
+	}
+	defer reopened.Close()
+
+	if !equalMap(reopened.Entries(), map[string]string{"k1": "v3", "k3": "v1"}) {
+		t.Errorf("Entries() after compaction = %v", reopened.Entries())
+	}
+	if reopened.Len() != 2 {
+		t.Errorf("Len() after reopen = %d, want 2", reopened.Len())
+	}
+}
+
+// TestLogStoreTornRecord checks a record torn by a crash in the middle of a write is cut
+// off on open instead of making the log unreadable
+func TestLogStoreTornRecord(t *testing.T) {
+	tests := []struct {
+		name    string
+		log     string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "TornLastRecord",
+			log:  "{\"op\":\"put\",\"key\":\"k1\",\"value\":\"v1\"}\n{\"op\":\"put\",\"key\":\"k2\",\"va",
+			want: map[string]string{"k1": "v1"},
+		},
+		{
+			name: "LastRecordWithoutNewline",
+			log:  "{\"op\":\"put\",\"key\":\"k1\",\"value\":\"v1\"}\n{\"op\":\"delete\",\"key\":\"k1\"}",
+			want: map[string]string{"k1": "v1"},
+		},
+		{
+			name:    "CorruptCompleteRecord",
+			log:     "{\"op\":\"put\",\"key\":\"k1\",\"va\n{\"op\":\"put\",\"key\":\"k2\",\"value\":\"v2\"}\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "entries.log")
+			if err := os.WriteFile(path, []byte(tt.log), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			store, err := adapter.OpenLogStore(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OpenLogStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !equalMap(store.Entries(), tt.want) {
+				t.Errorf("Entries() = %v, want %v", store.Entries(), tt.want)
+			}
+
+			// the next write starts on a line of its own and the log replays cleanly
+			if err := store.AddEntry("k3", "v3"); err != nil {
+				t.Fatalf("AddEntry() error = %v", err)
+			}
+			store.Close()
+
+			reopened, err := adapter.OpenLogStore(path)
+			if err != nil {
+				t.Fatalf("OpenLogStore() after the recovery error = %v", err)
+			}
+			defer reopened.Close()
+			if got := reopened.Entries()["k3"]; got != "v3" || reopened.Len() != len(tt.want)+1 {
+				t.Errorf("Entries() after reopen = %v, Len() = %d", reopened.Entries(), reopened.Len())
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package adapter
+
+import (
+	"github.com/pkg/errors"
+)
+
+// RecordStreamer is the streaming legacy interface. It yields the records one at a time
+// in the style of iter.Seq so a large legacy dataset never has to be held in memory.
+type RecordStreamer interface {
+	StreamRecords(yield func(record string) bool) error
+}
+
+// Progress is the state of a streaming conversion reported after every batch
+type Progress struct {
+	Batches   int // Batches are the batches written so far
+	Converted int // Converted are the records written so far
+	Failed    int // Failed are the records skipped so far in SkipAndCollect mode
+}
+
+// ProgressFunc is called with the progress of a streaming conversion after every batch
+type ProgressFunc func(Progress)
+
+// StreamRecords will yield the records one at a time and implements the RecordStreamer interface
+func (r *RecordsAPI) StreamRecords(yield func(record string) bool) error {
+	return SliceSource(r.records)(yield)
+}
+
+// ConvertStream will convert the legacy records in batches of batchSize holding at most one
+// batch in memory. The records are streamed when the legacy API implements RecordStreamer.
+// The progress function, if not nil, is called after every batch. Transactional mode is not
+// supported as rolling back would mean remembering every record of the stream.
+func (a *RecordsAdapter) ConvertStream(batchSize int, progress ProgressFunc) (Progress, error) {
+	var state Progress
+
+	if batchSize < 1 {
+		return state, errors.New("batch size must be at least 1")
+	}
+	if a.errorMode == Transactional {
+		return state, errors.New("transactional mode is not supported when streaming")
+	}
+
+	var (
+		index  int
+		failed ConversionErrors
+		batch  = make([]Entry, 0, batchSize)
+	)
+
+	// flush writes the batch to the modern API and reuses its memory for the next one
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		for i, entry := range batch {
+			if err := a.addEntry(entry); err != nil {
+				a.audit(index-len(batch)+i, entry, OutcomeFailed, err)
+				recordErr := a.recordError(index-len(batch)+i, entry.Value, err)
+				if a.errorMode != SkipAndCollect {
+					return recordErr
+				}
+				failed = append(failed, recordErr)
+				state.Failed++
+				continue
+			}
+			a.audit(index-len(batch)+i, entry, OutcomeConverted, nil)
+			state.Converted++
+		}
+
+		batch = batch[:0]
+		state.Batches++
+		if progress != nil {
+			progress(state)
+		}
+		return nil
+	}
+
+	sink := func(entry Entry) error {
+		batch = append(batch, entry)
+		index++
+		if len(batch) == batchSize {
+			return flush()
+		}
+		return nil
+	}
+
+	if _, err := New(a.records, a.toEntry, sink).Convert(); err != nil {
+		return state, err
+	}
+	if err := flush(); err != nil {
+		return state, err
+	}
+
+	if index == 0 {
+		return state, errors.New("no records to convert")
+	}
+	if len(failed) > 0 {
+		return state, failed
+	}
+	return state, nil
+}
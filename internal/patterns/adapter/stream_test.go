@@ -0,0 +1,142 @@
+package adapter_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/patterns/adapter"
+)
+
+// syntheticLegacy streams n generated records without ever holding them in memory
+type syntheticLegacy struct {
+	n int
+}
+
+func (s syntheticLegacy) Records() []string {
+	panic("Records() must not be called when the legacy API can stream")
+}
+
+func (s syntheticLegacy) StreamRecords(yield func(string) bool) error {
+	for i := 0; i < s.n; i++ {
+		if !yield("record-" + strconv.Itoa(i)) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// countingModern is a ModernAPI that only counts the entries written to it
+type countingModern struct {
+	added int
+}
+
+func (c *countingModern) Entries() map[string]string { return nil }
+
+func (c *countingModern) AddEntry(key, value string) error {
+	c.added++
+	return nil
+}
+
+func TestConvertStream(t *testing.T) {
+	tests := []struct {
+		name        string
+		legacy      adapter.LegacyAPI
+		batchSize   int
+		mode        adapter.ErrorMode
+		wantBatches []int // Converted reported after each batch
+		wantFinal   adapter.Progress
+		wantErr     bool
+	}{
+		{
+			name:        "StreamsInBatches",
+			legacy:      syntheticLegacy{n: 5},
+			batchSize:   2,
+			wantBatches: []int{2, 4, 5},
+			wantFinal:   adapter.Progress{Batches: 3, Converted: 5},
+		},
+		{
+			name:        "FallsBackToRecords",
+			legacy:      &stubLegacy{records: []string{"foo", "bar", "baz"}},
+			batchSize:   3,
+			wantBatches: []int{3},
+			wantFinal:   adapter.Progress{Batches: 1, Converted: 3},
+		},
+		{
+			name:        "SkipAndCollect",
+			legacy:      &stubLegacy{records: []string{"foo", "", "baz"}},
+			batchSize:   2,
+			mode:        adapter.SkipAndCollect,
+			wantBatches: []int{1, 2},
+			wantFinal:   adapter.Progress{Batches: 2, Converted: 2, Failed: 1},
+			wantErr:     true,
+		},
+		{
+			name:      "FailFast",
+			legacy:    &stubLegacy{records: []string{"foo", "", "baz"}},
+			batchSize: 2,
+			wantFinal: adapter.Progress{Converted: 1},
+			wantErr:   true,
+		},
+		{
+			name:      "TransactionalUnsupported",
+			legacy:    syntheticLegacy{n: 1},
+			batchSize: 1,
+			mode:      adapter.Transactional,
+			wantErr:   true,
+		},
+		{
+			name:      "InvalidBatchSize",
+			legacy:    syntheticLegacy{n: 1},
+			batchSize: 0,
+			wantErr:   true,
+		},
+		{
+			name:      "NoRecords",
+			legacy:    syntheticLegacy{n: 0},
+			batchSize: 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adap := adapter.NewAdapter(tt.legacy, adapter.NewEntriesAPI(), adapter.WithErrorMode(tt.mode))
+
+			var batches []int
+			final, err := adap.ConvertStream(tt.batchSize, func(p adapter.Progress) {
+				batches = append(batches, p.Converted)
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConvertStream() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if final != tt.wantFinal {
+				t.Errorf("ConvertStream() progress = %+v, want %+v", final, tt.wantFinal)
+			}
+			if len(batches) != len(tt.wantBatches) {
+				t.Fatalf("progress calls = %v, want %v", batches, tt.wantBatches)
+			}
+			for i := range batches {
+				if batches[i] != tt.wantBatches[i] {
+					t.Errorf("progress calls = %v, want %v", batches, tt.wantBatches)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkConvertStream converts a million synthetic records in batches of 1000
+func BenchmarkConvertStream(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		modern := &countingModern{}
+		adap := adapter.NewAdapter(syntheticLegacy{n: 1_000_000}, modern, adapter.WithKeyStrategy(adapter.SequentialKeys()))
+
+		if _, err := adap.ConvertStream(1000, nil); err != nil {
+			b.Fatalf("ConvertStream() error = %v", err)
+		}
+		if modern.added != 1_000_000 {
+			b.Fatalf("converted %d records, want 1000000", modern.added)
+		}
+	}
+}
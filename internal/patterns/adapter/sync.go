@@ -0,0 +1,159 @@
+package adapter
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// CheckpointStore keeps track of the legacy records that were migrated by Sync
+// and the key each record got in the modern API
+type CheckpointStore interface {
+	Load() (map[string]string, error) // Load returns the migrated records mapped to their keys
+	Save(migrated map[string]string) error
+}
+
+// EntryRemover is implemented by the modern APIs that can delete entries
+type EntryRemover interface {
+	RemoveEntry(key string) error
+}
+
+// SyncOptions are the options of a Sync run
+type SyncOptions struct {
+	// Tombstone removes the entries of the records that are gone from the legacy API
+	Tombstone bool
+}
+
+// SyncReport is the summary of a Sync run
+type SyncReport struct {
+	Added   int // Added are the records migrated by this run
+	Skipped int // Skipped are the records migrated by an earlier run
+	Removed int // Removed are the migrated records no longer found in the legacy API
+}
+
+// String will return the summary of the report
+func (r SyncReport) String() string {
+	return fmt.Sprintf("added %d, skipped %d, removed %d", r.Added, r.Skipped, r.Removed)
+}
+
+// MemoryCheckpoint is the struct that implements an in memory CheckpointStore
+type MemoryCheckpoint struct {
+	mu       sync.Mutex
+	migrated map[string]string
+}
+
+// NewMemoryCheckpoint will return a new empty MemoryCheckpoint struct
+func NewMemoryCheckpoint() *MemoryCheckpoint {
+	return &MemoryCheckpoint{
+		migrated: make(map[string]string),
+	}
+}
+
+// Load will return a copy of the migrated records
+func (m *MemoryCheckpoint) Load() (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return copyMap(m.migrated), nil
+}
+
+// Save will replace the migrated records with a copy of the given ones
+func (m *MemoryCheckpoint) Save(migrated map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.migrated = copyMap(migrated)
+	return nil
+}
+
+// Sync will incrementally migrate the legacy records. Records found in the checkpoint are
+// skipped so only new records are added on subsequent runs. Records in the checkpoint that
+// are gone from the legacy API are counted as removed and, with the Tombstone option, their
+// entries are removed from the modern API. Records are tracked by value so a record that
+// appears more than once in the legacy API is migrated once. When the legacy records can not
+// be read Sync changes nothing, an unreadable source must not look like every record is gone.
+func (a *RecordsAdapter) Sync(checkpoint CheckpointStore, opts SyncOptions) (SyncReport, error) {
+	var report SyncReport
+
+	migrated, err := checkpoint.Load()
+	if err != nil {
+		return report, errors.Wrap(err, "could not load checkpoint")
+	}
+
+	// read every record up front so a failed read aborts before anything is tombstoned
+	records, err := a.legacyRecords()
+	if err != nil {
+		return report, errors.Wrap(err, "could not read legacy records")
+	}
+
+	seen := make(map[string]bool)
+	for _, record := range records {
+		seen[record] = true
+
+		if _, ok := migrated[record]; ok {
+			report.Skipped++
+			continue
+		}
+
+		entry, err := a.toEntry(record)
+		if err == nil {
+			err = a.addEntry(entry)
+		}
+		if err != nil {
+			// keep the progress made so far so the next run does not redo it
+			return report, saveCheckpoint(checkpoint, migrated, err)
+		}
+
+		migrated[record] = entry.Key
+		report.Added++
+	}
+
+	// find the migrated records that are gone from the legacy API in a stable order
+	var removed []string
+	for record := range migrated {
+		if !seen[record] {
+			removed = append(removed, record)
+		}
+	}
+	sort.Strings(removed)
+
+	for _, record := range removed {
+		report.Removed++
+		if !opts.Tombstone {
+			continue
+		}
+
+		remover, ok := a.modern.(EntryRemover)
+		if !ok {
+			return report, saveCheckpoint(checkpoint, migrated, errors.New("modern API can not remove entries"))
+		}
+		if err := remover.RemoveEntry(migrated[record]); err != nil {
+			return report, saveCheckpoint(checkpoint, migrated, err)
+		}
+		delete(migrated, record)
+	}
+
+	return report, saveCheckpoint(checkpoint, migrated, nil)
+}
+
+/*##################################################################################
+# Helper Functions
+##################################################################################*/
+
+// saveCheckpoint will save the checkpoint and return the cause error if there is one
+// otherwise the error of saving the checkpoint
+func saveCheckpoint(checkpoint CheckpointStore, migrated map[string]string, cause error) error {
+	if err := checkpoint.Save(migrated); err != nil && cause == nil {
+		return errors.Wrap(err, "could not save checkpoint")
+	}
+	return cause
+}
+
+// copyMap will return a shallow copy of the map
+func copyMap(m map[string]string) map[string]string {
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
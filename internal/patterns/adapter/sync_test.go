@@ -0,0 +1,141 @@
+package adapter_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/patterns/adapter"
+)
+
+// stubLegacy is a LegacyAPI whose records can be changed between runs
+type stubLegacy struct {
+	records []string
+}
+
+func (s *stubLegacy) Records() []string {
+	return s.records
+}
+
+func TestRecordsAdapterSync(t *testing.T) {
+	tests := []struct {
+		name        string
+		first       []string
+		second      []string
+		opts        adapter.SyncOptions
+		wantReport  adapter.SyncReport
+		wantEntries []string
+	}{
+		{
+			name:        "UnchangedSkipsEverything",
+			first:       []string{"foo", "bar"},
+			second:      []string{"foo", "bar"},
+			wantReport:  adapter.SyncReport{Skipped: 2},
+			wantEntries: []string{"bar", "foo"},
+		},
+		{
+			name:        "OnlyNewRecordsAdded",
+			first:       []string{"foo"},
+			second:      []string{"foo", "bar", "baz"},
+			wantReport:  adapter.SyncReport{Added: 2, Skipped: 1},
+			wantEntries: []string{"bar", "baz", "foo"},
+		},
+		{
+			name:        "RemovedDetectedWithoutTombstone",
+			first:       []string{"foo", "bar"},
+			second:      []string{"foo"},
+			wantReport:  adapter.SyncReport{Skipped: 1, Removed: 1},
+			wantEntries: []string{"bar", "foo"},
+		},
+		{
+			name:        "RemovedTombstoned",
+			first:       []string{"foo", "bar"},
+			second:      []string{"foo"},
+			opts:        adapter.SyncOptions{Tombstone: true},
+			wantReport:  adapter.SyncReport{Skipped: 1, Removed: 1},
+			wantEntries: []string{"foo"},
+		},
+		{
+			name:        "DuplicateRecordMigratedOnce",
+			first:       []string{"foo", "foo"},
+			second:      []string{"foo", "foo"},
+			wantReport:  adapter.SyncReport{Skipped: 2},
+			wantEntries: []string{"foo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			legacy := &stubLegacy{records: tt.first}
+			modernAPI := adapter.NewEntriesAPI()
+			checkpoint := adapter.NewMemoryCheckpoint()
+			adap := adapter.NewAdapter(legacy, modernAPI)
+
+			if _, err := adap.Sync(checkpoint, tt.opts); err != nil {
+				t.Fatalf("first Sync() error = %v", err)
+			}
+
+			legacy.records = tt.second
+			report, err := adap.Sync(checkpoint, tt.opts)
+			if err != nil {
+				t.Fatalf("second Sync() error = %v", err)
+			}
+
+			if report != tt.wantReport {
+				t.Errorf("Sync() report = %v, want %v", report, tt.wantReport)
+			}
+			if got := sortedValues(modernAPI.Entries()); !equalSlice(got, tt.wantEntries) {
+				t.Errorf("entries = %v, want %v", got, tt.wantEntries)
+			}
+		})
+	}
+}
+
+func TestRecordsAdapterSyncTombstoneReadded(t *testing.T) {
+	legacy := &stubLegacy{records: []string{"foo"}}
+	modernAPI := adapter.NewEntriesAPI()
+	checkpoint := adapter.NewMemoryCheckpoint()
+	adap := adapter.NewAdapter(legacy, modernAPI)
+	opts := adapter.SyncOptions{Tombstone: true}
+
+	adap.Sync(checkpoint, opts)
+	legacy.records = nil
+	adap.Sync(checkpoint, opts)
+	legacy.records = []string{"foo"}
+
+	report, err := adap.Sync(checkpoint, opts)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if report.Added != 1 || len(modernAPI.Entries()) != 1 {
+		t.Errorf("Sync() report = %v entries = %v, want foo added again", report, modernAPI.Entries())
+	}
+}
+
+// TestRecordsAdapterSyncSourceFails checks an unreachable legacy service aborts the Sync
+// instead of tombstoning every migrated record
+func TestRecordsAdapterSyncSourceFails(t *testing.T) {
+	server := adapter.StartLegacyStub("foo", "bar", "baz")
+	modernAPI := adapter.NewEntriesAPI()
+	checkpoint := adapter.NewMemoryCheckpoint()
+	adap := adapter.NewAdapter(adapter.NewHTTPRecordsAPI(server.URL, server.Client()), modernAPI)
+	opts := adapter.SyncOptions{Tombstone: true}
+
+	if _, err := adap.Sync(checkpoint, opts); err != nil {
+		t.Fatalf("first Sync() error = %v", err)
+	}
+	server.Close()
+
+	report, err := adap.Sync(checkpoint, opts)
+	if err == nil || !strings.Contains(err.Error(), "could not read legacy records") {
+		t.Errorf("Sync() error = %v, want the read error", err)
+	}
+	if report != (adapter.SyncReport{}) {
+		t.Errorf("Sync() report = %v, want nothing done", report)
+	}
+	if modernAPI.Len() != 3 {
+		t.Errorf("Len() = %d, want every entry kept", modernAPI.Len())
+	}
+	if migrated, _ := checkpoint.Load(); len(migrated) != 3 {
+		t.Errorf("checkpoint = %v, want it unchanged", migrated)
+	}
+}
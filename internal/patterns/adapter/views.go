@@ -0,0 +1,101 @@
+package adapter
+
+// The views are read only snapshots of the legacy records and modern entries. A view owns its
+// own copy of the data so it can be handed out freely: changing the store afterwards does not
+// change the view and nothing done with the view reaches the store.
+
+import (
+	"slices"
+	"sort"
+)
+
+// EntriesView is a read only snapshot of modern entries
+type EntriesView struct {
+	entries map[string]string
+}
+
+// newEntriesView will return the view of a copy of the entries
+func newEntriesView(entries map[string]string) EntriesView {
+	return EntriesView{entries: copyMap(entries)}
+}
+
+// Get will return the value of the key
+func (v EntriesView) Get(key string) (string, bool) {
+	value, ok := v.entries[key]
+	return value, ok
+}
+
+// Len will return the number of entries
+func (v EntriesView) Len() int {
+	return len(v.entries)
+}
+
+// Keys will return the keys in sorted order
+func (v EntriesView) Keys() []string {
+	return sortedKeys(v.entries)
+}
+
+// Range will call fn with every entry in key order until fn returns false
+func (v EntriesView) Range(fn func(key string, value string) bool) {
+	for _, key := range v.Keys() {
+		if !fn(key, v.entries[key]) {
+			return
+		}
+	}
+}
+
+// Entries will return a copy of the entries
+func (v EntriesView) Entries() map[string]string {
+	return copyMap(v.entries)
+}
+
+// RecordsView is a read only snapshot of legacy records
+type RecordsView struct {
+	records []string
+}
+
+// newRecordsView will return the view of a copy of the records
+func newRecordsView(records []string) RecordsView {
+	return RecordsView{records: slices.Clone(records)}
+}
+
+// Get will return the record at the index
+func (v RecordsView) Get(index int) (string, bool) {
+	if index < 0 || index >= len(v.records) {
+		return "", false
+	}
+	return v.records[index], true
+}
+
+// Len will return the number of records
+func (v RecordsView) Len() int {
+	return len(v.records)
+}
+
+// Range will call fn with every record in order until fn returns false
+func (v RecordsView) Range(fn func(index int, record string) bool) {
+	for i, record := range v.records {
+		if !fn(i, record) {
+			return
+		}
+	}
+}
+
+// Records will return a copy of the records
+func (v RecordsView) Records() []string {
+	return slices.Clone(v.records)
+}
+
+/*##################################################################################
+# Helper Functions
+##################################################################################*/
+
+// sortedKeys will return the keys of the map in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
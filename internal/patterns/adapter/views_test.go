@@ -0,0 +1,159 @@
+package adapter_test
+
+import (
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/patterns/adapter"
+)
+
+func TestEntriesAPIMutationDoesNotLeak(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(api *adapter.EntriesAPI)
+	}{
+		{
+			name:   "EntriesChanged",
+			mutate: func(api *adapter.EntriesAPI) { api.Entries()["k1"] = "changed" },
+		},
+		{
+			name:   "EntriesAddedEmpty",
+			mutate: func(api *adapter.EntriesAPI) { api.Entries()[""] = "" },
+		},
+		{
+			name:   "EntriesDeleted",
+			mutate: func(api *adapter.EntriesAPI) { delete(api.Entries(), "k1") },
+		},
+		{
+			name:   "SnapshotEntriesChanged",
+			mutate: func(api *adapter.EntriesAPI) { api.Snapshot().Entries()["k1"] = "changed" },
+		},
+		{
+			name:   "KeysChanged",
+			mutate: func(api *adapter.EntriesAPI) { api.Keys()[0] = "changed" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := adapter.NewEntriesAPI()
+			api.AddEntry("k1", "v1")
+			api.AddEntry("k2", "v2")
+
+			tt.mutate(api)
+
+			if !equalMap(api.Entries(), map[string]string{"k1": "v1", "k2": "v2"}) {
+				t.Errorf("mutation leaked into the EntriesAPI: %v", api.Entries())
+			}
+		})
+	}
+}
+
+func TestEntriesAPIQueries(t *testing.T) {
+	api := adapter.NewEntriesAPI()
+	api.AddEntry("b", "2")
+	api.AddEntry("a", "1")
+	api.AddEntry("c", "3")
+
+	if value, ok := api.Get("b"); !ok || value != "2" {
+		t.Errorf("Get(b) = %q, %v, want 2, true", value, ok)
+	}
+	if _, ok := api.Get("missing"); ok {
+		t.Errorf("Get(missing) found an entry")
+	}
+	if api.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", api.Len())
+	}
+	if !equalSlice(api.Keys(), []string{"a", "b", "c"}) {
+		t.Errorf("Keys() = %v, want [a b c]", api.Keys())
+	}
+
+	var ranged []string
+	api.Range(func(key, value string) bool {
+		ranged = append(ranged, key+"="+value)
+		// writing while ranging must not deadlock
+		api.AddEntry("d", "4")
+		return key != "b"
+	})
+	if !equalSlice(ranged, []string{"a=1", "b=2"}) {
+		t.Errorf("Range() visited %v, want [a=1 b=2]", ranged)
+	}
+}
+
+func TestEntriesSnapshotIsStable(t *testing.T) {
+	api := adapter.NewEntriesAPI()
+	api.AddEntry("k1", "v1")
+
+	snapshot := api.Snapshot()
+	api.AddEntry("k1", "changed")
+	api.AddEntry("k2", "v2")
+
+	if value, _ := snapshot.Get("k1"); value != "v1" || snapshot.Len() != 1 {
+		t.Errorf("Snapshot() changed with the EntriesAPI: %v", snapshot.Entries())
+	}
+	if !equalSlice(snapshot.Keys(), []string{"k1"}) {
+		t.Errorf("Snapshot().Keys() = %v, want [k1]", snapshot.Keys())
+	}
+}
+
+func TestRecordsAPIMutationDoesNotLeak(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(api *adapter.RecordsAPI)
+	}{
+		{
+			name:   "RecordsChanged",
+			mutate: func(api *adapter.RecordsAPI) { api.Records()[0] = "changed" },
+		},
+		{
+			name: "RecordsAppendedToCopy",
+			mutate: func(api *adapter.RecordsAPI) {
+				records := api.Records()
+				_ = append(records[:1], "changed")
+			},
+		},
+		{
+			name:   "SnapshotRecordsChanged",
+			mutate: func(api *adapter.RecordsAPI) { api.Snapshot().Records()[0] = "changed" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := adapter.NewRecordsAPI()
+
+			tt.mutate(api)
+
+			if !equalSlice(api.Records(), []string{"foo", "bar", "baz"}) {
+				t.Errorf("mutation leaked into the RecordsAPI: %v", api.Records())
+			}
+		})
+	}
+}
+
+func TestRecordsAPIQueries(t *testing.T) {
+	api := adapter.NewRecordsAPI()
+	snapshot := api.Snapshot()
+	api.SetRecord(0, "changed")
+
+	if record, ok := api.Get(0); !ok || record != "changed" {
+		t.Errorf("Get(0) = %q, %v, want changed, true", record, ok)
+	}
+	if _, ok := api.Get(3); ok {
+		t.Errorf("Get(3) found a record")
+	}
+	if api.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", api.Len())
+	}
+	if record, _ := snapshot.Get(0); record != "foo" {
+		t.Errorf("Snapshot() changed with the RecordsAPI: %v", snapshot.Records())
+	}
+
+	var ranged []string
+	api.Range(func(index int, record string) bool {
+		ranged = append(ranged, record)
+		return index < 1
+	})
+	if !equalSlice(ranged, []string{"changed", "bar"}) {
+		t.Errorf("Range() visited %v, want [changed bar]", ranged)
+	}
+}
@@ -0,0 +1,257 @@
+package singleton
+
+// The ChannelOperator is the single point of monitoring channels. Channels are registered
+// under a name and every send and receive goes through the operator so it can count them
+// and report the state of every channel in a snapshot. All the methods are safe for
+// concurrent use.
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrChannelExists is returned when registering a name that is already registered
+	ErrChannelExists = errors.New("channel already registered")
+	// ErrNoChannel is returned for a name that is not registered
+	ErrNoChannel = errors.New("channel not registered")
+	// ErrChannelClosed is returned when the channel is unregistered or closed
+	ErrChannelClosed = errors.New("channel closed")
+	// ErrTimeout is returned when a send or receive does not complete within its timeout
+	ErrTimeout = errors.New("channel operation timed out")
+)
+
+// channel is a registered channel with its counters
+type channel struct {
+	ch   chan interface{}
+	done chan struct{} // done is closed first when the channel is closed
+	// mu is held for reading by the senders so the channel is never closed under a send
+	mu       sync.RWMutex
+	sent     atomic.Int64
+	received atomic.Int64
+	dropped  atomic.Int64
+}
+
+// ChannelStats is the state of a registered channel at the time of a snapshot
+type ChannelStats struct {
+	Name     string
+	Len      int   // Len is the number of values in the buffer
+	Cap      int   // Cap is the size of the buffer
+	Sent     int64 // Sent is the number of values sent through the operator
+	Received int64 // Received is the number of values received through the operator
+	Dropped  int64 // Dropped is the number of published or forwarded values that were dropped
+}
+
+// String will return a description of the channel state
+func (s ChannelStats) String() string {
+	return fmt.Sprintf("%s: len %d cap %d sent %d received %d dropped %d",
+		s.Name, s.Len, s.Cap, s.Sent, s.Received, s.Dropped)
+}
+
+// Register will register a new channel under the name with a buffer of capacity values
+func (c *ChannelOperator) Register(name string, capacity int) error {
+	if capacity < 0 {
+		return fmt.Errorf("capacity of channel %s must not be negative", name)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.channels[name]; ok {
+		return fmt.Errorf("%w: %s", ErrChannelExists, name)
+	}
+	c.channels[name] = &channel{
+		ch:   make(chan interface{}, capacity),
+		done: make(chan struct{}),
+	}
+
+	return nil
+}
+
+// Unregister will close the channel of the name and remove it from the channels and the
+// topics it is subscribed to, the sends and receives waiting on it return ErrChannelClosed
+func (c *ChannelOperator) Unregister(name string) error {
+	c.mu.Lock()
+	ch, ok := c.channels[name]
+	delete(c.channels, name)
+	for topic := range c.topics {
+		c.unsubscribe(topic, name)
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoChannel, name)
+	}
+	ch.close()
+
+	return nil
+}
+
+// Send will send the value on the channel of the name waiting at most the timeout for room
+// in the buffer, a timeout of 0 or less only sends when there is room right away
+func (c *ChannelOperator) Send(name string, value interface{}, timeout time.Duration) error {
+	ch, err := c.lookup(name)
+	if err != nil {
+		return err
+	}
+
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+
+	// a closed channel must not be sent on even when its buffer has room
+	select {
+	case <-ch.done:
+		return fmt.Errorf("%w: %s", ErrChannelClosed, name)
+	default:
+	}
+
+	select {
+	case ch.ch <- value:
+		ch.sent.Add(1)
+		return nil
+	default:
+		if timeout <= 0 {
+			return fmt.Errorf("%w: send on %s", ErrTimeout, name)
+		}
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case ch.ch <- value:
+		ch.sent.Add(1)
+		return nil
+	case <-ch.done:
+		return fmt.Errorf("%w: %s", ErrChannelClosed, name)
+	case <-timer.C:
+		return fmt.Errorf("%w: send on %s", ErrTimeout, name)
+	}
+}
+
+// Receive will receive a value from the channel of the name waiting at most the timeout,
+// a timeout of 0 or less only receives a value that is already in the buffer
+func (c *ChannelOperator) Receive(name string, timeout time.Duration) (interface{}, error) {
+	ch, err := c.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case value, ok := <-ch.ch:
+		return ch.receive(name, value, ok)
+	default:
+		if timeout <= 0 {
+			return nil, fmt.Errorf("%w: receive on %s", ErrTimeout, name)
+		}
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case value, ok := <-ch.ch:
+		return ch.receive(name, value, ok)
+	case <-ch.done:
+		return nil, fmt.Errorf("%w: %s", ErrChannelClosed, name)
+	case <-timer.C:
+		return nil, fmt.Errorf("%w: receive on %s", ErrTimeout, name)
+	}
+}
+
+// CloseAll will close and unregister every channel and remove every subscription
+func (c *ChannelOperator) CloseAll() {
+	c.mu.Lock()
+	channels := c.channels
+	c.channels = make(map[string]*channel)
+	c.topics = make(map[string][]subscription)
+	c.mu.Unlock()
+
+	for _, ch := range channels {
+		ch.close()
+	}
+}
+
+// Names will return the names of the registered channels in order
+func (c *ChannelOperator) Names() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.channels))
+	for name := range c.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stats will return the state of the channel of the name
+func (c *ChannelOperator) Stats(name string) (ChannelStats, error) {
+	ch, err := c.lookup(name)
+	if err != nil {
+		return ChannelStats{}, err
+	}
+	return ch.stats(name), nil
+}
+
+// Snapshot will return the state of every registered channel ordered by name
+func (c *ChannelOperator) Snapshot() []ChannelStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snapshot := make([]ChannelStats, 0, len(c.channels))
+	for name, ch := range c.channels {
+		snapshot = append(snapshot, ch.stats(name))
+	}
+	sort.Slice(snapshot, func(i, j int) bool { return snapshot[i].Name < snapshot[j].Name })
+	return snapshot
+}
+
+/*##################################################################################
+# Helper Functions
+##################################################################################*/
+
+// lookup will return the registered channel of the name
+func (c *ChannelOperator) lookup(name string) (*channel, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ch, ok := c.channels[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoChannel, name)
+	}
+	return ch, nil
+}
+
+// close will release the senders and receivers waiting on the channel then close it
+func (ch *channel) close() {
+	close(ch.done)
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	close(ch.ch)
+}
+
+// receive will count a value received from the channel, ok is false when it was closed
+func (ch *channel) receive(name string, value interface{}, ok bool) (interface{}, error) {
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrChannelClosed, name)
+	}
+	ch.received.Add(1)
+	return value, nil
+}
+
+// stats will return the state of the channel under the name
+func (ch *channel) stats(name string) ChannelStats {
+	return ChannelStats{
+		Name:     name,
+		Len:      len(ch.ch),
+		Cap:      cap(ch.ch),
+		Sent:     ch.sent.Load(),
+		Received: ch.received.Load(),
+		Dropped:  ch.dropped.Load(),
+	}
+}
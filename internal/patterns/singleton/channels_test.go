@@ -0,0 +1,229 @@
+package singleton_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lkendrickd/patterns/internal/patterns/singleton"
+)
+
+func TestRegisterUnregister(t *testing.T) {
+	op := singleton.NewChannelOperator()
+
+	if err := op.Register("jobs", 2); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := op.Register("jobs", 2); !errors.Is(err, singleton.ErrChannelExists) {
+		t.Errorf("Register() twice error = %v, want ErrChannelExists", err)
+	}
+	if err := op.Register("bad", -1); err == nil {
+		t.Errorf("Register() with a negative capacity error = nil")
+	}
+	op.Register("alerts", 0)
+
+	if got := op.Names(); len(got) != 2 || got[0] != "alerts" || got[1] != "jobs" {
+		t.Errorf("Names() = %v, want [alerts jobs]", got)
+	}
+
+	if err := op.Unregister("jobs"); err != nil {
+		t.Errorf("Unregister() error = %v", err)
+	}
+	if err := op.Unregister("jobs"); !errors.Is(err, singleton.ErrNoChannel) {
+		t.Errorf("Unregister() twice error = %v, want ErrNoChannel", err)
+	}
+	if err := op.Send("jobs", 1, 0); !errors.Is(err, singleton.ErrNoChannel) {
+		t.Errorf("Send() after Unregister error = %v, want ErrNoChannel", err)
+	}
+}
+
+func TestSendReceive(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		run      func(op *singleton.ChannelOperator) error
+		wantErr  error
+	}{
+		{
+			name:     "BufferedRoundTrip",
+			capacity: 1,
+			run: func(op *singleton.ChannelOperator) error {
+				if err := op.Send("c", "v", 0); err != nil {
+					return err
+				}
+				value, err := op.Receive("c", 0)
+				if err == nil && value != "v" {
+					return fmt.Errorf("received %v, want v", value)
+				}
+				return err
+			},
+		},
+		{
+			name:     "SendTimesOutOnFullBuffer",
+			capacity: 1,
+			run: func(op *singleton.ChannelOperator) error {
+				op.Send("c", 1, 0)
+				return op.Send("c", 2, 10*time.Millisecond)
+			},
+			wantErr: singleton.ErrTimeout,
+		},
+		{
+			name:     "SendWithoutWaitingOnUnbuffered",
+			capacity: 0,
+			run: func(op *singleton.ChannelOperator) error {
+				return op.Send("c", 1, 0)
+			},
+			wantErr: singleton.ErrTimeout,
+		},
+		{
+			name:     "ReceiveTimesOutOnEmptyBuffer",
+			capacity: 1,
+			run: func(op *singleton.ChannelOperator) error {
+				_, err := op.Receive("c", 10*time.Millisecond)
+				return err
+			},
+			wantErr: singleton.ErrTimeout,
+		},
+		{
+			name:     "UnbufferedHandOff",
+			capacity: 0,
+			run: func(op *singleton.ChannelOperator) error {
+				go op.Send("c", "v", time.Second)
+				_, err := op.Receive("c", time.Second)
+				return err
+			},
+		},
+		{
+			name:     "UnknownChannel",
+			capacity: 1,
+			run: func(op *singleton.ChannelOperator) error {
+				_, err := op.Receive("missing", 0)
+				return err
+			},
+			wantErr: singleton.ErrNoChannel,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := singleton.NewChannelOperator()
+			op.Register("c", tt.capacity)
+			defer op.CloseAll()
+
+			if err := tt.run(op); !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUnregisterReleasesWaiters(t *testing.T) {
+	op := singleton.NewChannelOperator()
+	op.Register("full", 0)
+	op.Register("empty", 0)
+
+	errs := make(chan error, 2)
+	go func() { errs <- op.Send("full", 1, time.Minute) }()
+	go func() {
+		_, err := op.Receive("empty", time.Minute)
+		errs <- err
+	}()
+
+	// give both a moment to start waiting
+	time.Sleep(20 * time.Millisecond)
+	op.CloseAll()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, singleton.ErrChannelClosed) {
+				t.Errorf("waiting operation error = %v, want ErrChannelClosed", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("CloseAll() did not release the waiting operations")
+		}
+	}
+	if len(op.Names()) != 0 {
+		t.Errorf("Names() = %v after CloseAll", op.Names())
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	op := singleton.NewChannelOperator()
+	op.Register("b", 4)
+	op.Register("a", 2)
+
+	op.Send("b", 1, 0)
+	op.Send("b", 2, 0)
+	op.Send("b", 3, 0)
+	op.Receive("b", 0)
+	op.Send("a", 1, 0)
+
+	want := []string{
+		"a: len 1 cap 2 sent 1 received 0 dropped 0",
+		"b: len 2 cap 4 sent 3 received 1 dropped 0",
+	}
+	snapshot := op.Snapshot()
+	if len(snapshot) != len(want) {
+		t.Fatalf("Snapshot() = %v, want %v", snapshot, want)
+	}
+	for i, stats := range snapshot {
+		if stats.String() != want[i] {
+			t.Errorf("Snapshot()[%d] = %q, want %q", i, stats, want[i])
+		}
+	}
+
+	if stats, err := op.Stats("b"); err != nil || stats.Sent != 3 {
+		t.Errorf("Stats(b) = %v, %v", stats, err)
+	}
+}
+
+// TestConcurrentUse sends and receives on shared channels while others are registered and
+// unregistered, run it with -race
+func TestConcurrentUse(t *testing.T) {
+	op := singleton.NewChannelOperator()
+	op.Register("shared", 8)
+
+	const senders, perSender = 4, 50
+
+	var wg sync.WaitGroup
+	for s := 0; s < senders; s++ {
+		wg.Add(1)
+		go func(s int) {
+			defer wg.Done()
+			for i := 0; i < perSender; i++ {
+				if err := op.Send("shared", i, time.Second); err != nil {
+					t.Errorf("Send() error = %v", err)
+					return
+				}
+			}
+		}(s)
+	}
+
+	// churn other channels while the shared one is busy
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			name := fmt.Sprintf("temp-%d", i)
+			op.Register(name, 1)
+			op.Send(name, i, 0)
+			op.Snapshot()
+			op.Unregister(name)
+		}
+	}()
+
+	for i := 0; i < senders*perSender; i++ {
+		if _, err := op.Receive("shared", time.Second); err != nil {
+			t.Fatalf("Receive() error = %v", err)
+		}
+	}
+	wg.Wait()
+
+	stats, _ := op.Stats("shared")
+	if stats.Sent != senders*perSender || stats.Received != senders*perSender || stats.Len != 0 {
+		t.Errorf("Stats(shared) = %v, want every value sent and received", stats)
+	}
+}
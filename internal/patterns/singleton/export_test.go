@@ -0,0 +1,5 @@
+package singleton
+
+// NewChannelOperator exposes the constructor to the tests so each test can get its own
+// ChannelOperator, directly or through a Holder, instead of sharing the singleton
+var NewChannelOperator = newChannelOperator
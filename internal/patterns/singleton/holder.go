@@ -0,0 +1,52 @@
+package singleton
+
+// The Holder is the once and instance pair behind a singleton packaged as a type. The
+// package keeps its global singleton in a Holder and a test can make a Holder of its own
+// to get an instance nobody else shares, so tests neither see each other's state nor
+// depend on the order they run in.
+
+import "sync"
+
+// Holder lazily creates a single instance of T and returns it on every Get
+type Holder[T any] struct {
+	newInstance func() T
+
+	mu    sync.RWMutex
+	state *holderState[T]
+}
+
+// holderState is one generation of the holder, Reset replaces it with a new one
+type holderState[T any] struct {
+	once     sync.Once
+	instance T
+}
+
+// NewHolder will return a Holder creating its instance with newInstance on the first Get
+func NewHolder[T any](newInstance func() T) *Holder[T] {
+	return &Holder[T]{
+		newInstance: newInstance,
+		state:       &holderState[T]{},
+	}
+}
+
+// Get will return the instance creating it on the first call, concurrent first calls
+// wait for the one creating it so newInstance runs once
+func (h *Holder[T]) Get() T {
+	h.mu.RLock()
+	state := h.state
+	h.mu.RUnlock()
+
+	state.once.Do(func() {
+		state.instance = h.newInstance()
+	})
+	return state.instance
+}
+
+// Reset will forget the instance so the next Get creates a new one, callers still
+// holding the old instance keep using it
+func (h *Holder[T]) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.state = &holderState[T]{}
+}
@@ -0,0 +1,102 @@
+package singleton_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lkendrickd/patterns/internal/patterns/singleton"
+)
+
+func TestHolder(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		run  func(t *testing.T, holder *singleton.Holder[*singleton.ChannelOperator])
+	}{
+		{
+			name: "GetReturnsTheSameInstance",
+			run: func(t *testing.T, holder *singleton.Holder[*singleton.ChannelOperator]) {
+				if first, second := holder.Get(), holder.Get(); first != second {
+					t.Errorf("Get() = %s then %s, want the same instance", first.ID, second.ID)
+				}
+			},
+		},
+		{
+			name: "ResetCreatesANewInstance",
+			run: func(t *testing.T, holder *singleton.Holder[*singleton.ChannelOperator]) {
+				before := holder.Get()
+				before.Register("jobs", 1)
+				holder.Reset()
+
+				after := holder.Get()
+				if after == before || after.ID == before.ID {
+					t.Errorf("Get() after Reset() = %s, want a new instance", after.ID)
+				}
+				if names := after.Names(); len(names) != 0 {
+					t.Errorf("Names() after Reset() = %v, want a fresh operator", names)
+				}
+				// the old instance is still usable by whoever holds it
+				if names := before.Names(); len(names) != 1 {
+					t.Errorf("Names() of the old instance = %v, want [jobs]", names)
+				}
+			},
+		},
+		{
+			name: "IsolatedFromTheGlobal",
+			run: func(t *testing.T, holder *singleton.Holder[*singleton.ChannelOperator]) {
+				if holder.Get() == singleton.New() {
+					t.Error("Get() returned the global singleton")
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tt.run(t, singleton.NewHolder(singleton.NewChannelOperator))
+		})
+	}
+}
+
+// TestHolderConcurrentGet calls Get and Reset from many goroutines, run it with -race
+func TestHolderConcurrentGet(t *testing.T) {
+	var created atomic.Int64
+	holder := singleton.NewHolder(func() *singleton.ChannelOperator {
+		created.Add(1)
+		return singleton.NewChannelOperator()
+	})
+
+	const getters = 16
+
+	var wg sync.WaitGroup
+	instances := make([]*singleton.ChannelOperator, getters)
+	for g := 0; g < getters; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			instances[g] = holder.Get()
+		}(g)
+	}
+	wg.Wait()
+
+	if created.Load() != 1 {
+		t.Errorf("created %d instances, want 1", created.Load())
+	}
+	for _, instance := range instances {
+		if instance != instances[0] {
+			t.Fatal("Get() returned different instances")
+		}
+	}
+
+	for g := 0; g < getters; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			holder.Reset()
+			holder.Get().Names()
+		}()
+	}
+	wg.Wait()
+}
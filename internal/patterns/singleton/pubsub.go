@@ -0,0 +1,242 @@
+package singleton
+
+// Publish and subscribe on top of the registered channels. A topic has subscribers which are
+// registered channels, publishing a message to the topic sends it to every one of them and
+// each subscriber decides with its backpressure policy what happens when it falls behind.
+// FanIn is the other direction and merges several registered channels into one.
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Backpressure is what happens to a message for a subscriber whose buffer is full
+type Backpressure int
+
+const (
+	// DropNewest drops the message that does not fit
+	DropNewest Backpressure = iota
+	// DropOldest drops the oldest message in the buffer to make room for the new one
+	DropOldest
+	// Block makes the publisher wait up to its timeout for room and then drops the message
+	Block
+)
+
+// String will return the name of the policy
+func (b Backpressure) String() string {
+	switch b {
+	case DropOldest:
+		return "drop-oldest"
+	case Block:
+		return "block"
+	default:
+		return "drop-newest"
+	}
+}
+
+// subscription is a registered channel subscribed to a topic
+type subscription struct {
+	channel string
+	policy  Backpressure
+}
+
+// PublishResult is the number of subscribers a message was delivered to and dropped for
+type PublishResult struct {
+	Delivered int
+	Dropped   int
+}
+
+// Subscribe will subscribe the registered channel to the topic with the backpressure policy
+func (c *ChannelOperator) Subscribe(topic string, channel string, policy Backpressure) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.channels[channel]; !ok {
+		return fmt.Errorf("%w: %s", ErrNoChannel, channel)
+	}
+	for _, s := range c.topics[topic] {
+		if s.channel == channel {
+			return fmt.Errorf("channel %s already subscribed to %s", channel, topic)
+		}
+	}
+	c.topics[topic] = append(c.topics[topic], subscription{channel: channel, policy: policy})
+
+	return nil
+}
+
+// Unsubscribe will remove the channel from the subscribers of the topic
+func (c *ChannelOperator) Unsubscribe(topic string, channel string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.unsubscribe(topic, channel) {
+		return fmt.Errorf("channel %s is not subscribed to %s", channel, topic)
+	}
+	return nil
+}
+
+// Subscribers will return the channels subscribed to the topic in the order they subscribed
+func (c *ChannelOperator) Subscribers(topic string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	channels := make([]string, len(c.topics[topic]))
+	for i, s := range c.topics[topic] {
+		channels[i] = s.channel
+	}
+	return channels
+}
+
+// Publish will send the message to every subscriber of the topic. A subscriber with the
+// Block policy is waited on for at most the timeout, the others never hold up the publisher.
+// A topic without subscribers drops nothing and delivers nothing.
+func (c *ChannelOperator) Publish(topic string, message interface{}, timeout time.Duration) PublishResult {
+	c.mu.RLock()
+	subscribers := append([]subscription(nil), c.topics[topic]...)
+	c.mu.RUnlock()
+
+	var result PublishResult
+	for _, s := range subscribers {
+		if c.deliver(s, message, timeout) {
+			result.Delivered++
+		} else {
+			result.Dropped++
+		}
+	}
+	return result
+}
+
+// FanIn will forward every value received on the source channels to the target channel
+// until stop is called or the target or a source is unregistered. The values of the
+// sources are interleaved in the order they arrive. stop waits for the forwarding to end.
+func (c *ChannelOperator) FanIn(target string, sources ...string) (stop func(), err error) {
+	to, err := c.lookup(target)
+	if err != nil {
+		return nil, err
+	}
+	from := make([]*channel, len(sources))
+	for i, source := range sources {
+		if source == target {
+			return nil, fmt.Errorf("channel %s can not fan in to itself", target)
+		}
+		if from[i], err = c.lookup(source); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		wg       sync.WaitGroup
+		done     = make(chan struct{})
+		stopOnce sync.Once
+	)
+	for _, source := range from {
+		wg.Add(1)
+		go func(source *channel) {
+			defer wg.Done()
+			for {
+				select {
+				case value, ok := <-source.ch:
+					if !ok {
+						return
+					}
+					source.received.Add(1)
+					if !to.forward(value, done) {
+						return
+					}
+				case <-source.done:
+					return
+				case <-to.done:
+					return
+				case <-done:
+					return
+				}
+			}
+		}(source)
+	}
+
+	return func() {
+		stopOnce.Do(func() { close(done) })
+		wg.Wait()
+	}, nil
+}
+
+/*##################################################################################
+# Helper Functions
+##################################################################################*/
+
+// deliver will send the message to the subscriber according to its policy and report
+// whether it was delivered, a message that is not delivered is counted as dropped
+func (c *ChannelOperator) deliver(s subscription, message interface{}, timeout time.Duration) bool {
+	ch, err := c.lookup(s.channel)
+	if err != nil {
+		return false
+	}
+
+	switch s.policy {
+	case Block:
+		err = c.Send(s.channel, message, timeout)
+	case DropOldest:
+		err = c.Send(s.channel, message, 0)
+		if errors.Is(err, ErrTimeout) {
+			// make room by dropping the oldest message, another sender may still take it
+			select {
+			case _, ok := <-ch.ch:
+				if ok {
+					ch.dropped.Add(1)
+				}
+			default:
+			}
+			err = c.Send(s.channel, message, 0)
+		}
+	default:
+		err = c.Send(s.channel, message, 0)
+	}
+
+	if err != nil {
+		ch.dropped.Add(1)
+		return false
+	}
+	return true
+}
+
+// unsubscribe will remove the channel from the topic and report whether it was subscribed,
+// it is called with the lock held
+func (c *ChannelOperator) unsubscribe(topic string, channel string) bool {
+	subscribers := c.topics[topic]
+	for i, s := range subscribers {
+		if s.channel == channel {
+			c.topics[topic] = append(subscribers[:i:i], subscribers[i+1:]...)
+			if len(c.topics[topic]) == 0 {
+				delete(c.topics, topic)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// forward will send the value on the channel until it is closed or done is closed and
+// report whether it was sent, a value that could not be forwarded is counted as dropped
+func (ch *channel) forward(value interface{}, done <-chan struct{}) bool {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+
+	select {
+	case <-ch.done:
+		ch.dropped.Add(1)
+		return false
+	default:
+	}
+
+	select {
+	case ch.ch <- value:
+		ch.sent.Add(1)
+		return true
+	case <-ch.done:
+	case <-done:
+	}
+	ch.dropped.Add(1)
+	return false
+}
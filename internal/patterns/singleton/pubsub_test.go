@@ -0,0 +1,295 @@
+package singleton_test
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lkendrickd/patterns/internal/patterns/singleton"
+)
+
+func TestSubscribe(t *testing.T) {
+	op := singleton.NewChannelOperator()
+	defer op.CloseAll()
+	op.Register("a", 1)
+	op.Register("b", 1)
+
+	if err := op.Subscribe("news", "missing", singleton.DropNewest); !errors.Is(err, singleton.ErrNoChannel) {
+		t.Errorf("Subscribe() unknown channel error = %v, want ErrNoChannel", err)
+	}
+	if err := op.Subscribe("news", "a", singleton.DropNewest); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if err := op.Subscribe("news", "a", singleton.Block); err == nil {
+		t.Errorf("Subscribe() twice error = nil")
+	}
+	op.Subscribe("news", "b", singleton.DropOldest)
+
+	if got := op.Subscribers("news"); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("Subscribers() = %v, want [a b]", got)
+	}
+
+	if err := op.Unsubscribe("news", "a"); err != nil {
+		t.Errorf("Unsubscribe() error = %v", err)
+	}
+	if err := op.Unsubscribe("news", "a"); err == nil {
+		t.Errorf("Unsubscribe() twice error = nil")
+	}
+
+	// unregistering a channel removes its subscriptions
+	op.Unregister("b")
+	if got := op.Subscribers("news"); len(got) != 0 {
+		t.Errorf("Subscribers() = %v after Unregister, want none", got)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      singleton.Backpressure
+		timeout     time.Duration
+		receiveLate bool // receiveLate receives the first message while the second is published
+		want        singleton.PublishResult
+		wantValues  []interface{}
+		wantDropped int64
+	}{
+		{
+			name:        "DropNewest",
+			policy:      singleton.DropNewest,
+			want:        singleton.PublishResult{Dropped: 1},
+			wantValues:  []interface{}{1},
+			wantDropped: 1,
+		},
+		{
+			name:        "DropOldest",
+			policy:      singleton.DropOldest,
+			want:        singleton.PublishResult{Delivered: 1},
+			wantValues:  []interface{}{2},
+			wantDropped: 1,
+		},
+		{
+			name:        "BlockTimesOut",
+			policy:      singleton.Block,
+			timeout:     10 * time.Millisecond,
+			want:        singleton.PublishResult{Dropped: 1},
+			wantValues:  []interface{}{1},
+			wantDropped: 1,
+		},
+		{
+			name:        "BlockWaitsForRoom",
+			policy:      singleton.Block,
+			timeout:     time.Second,
+			receiveLate: true,
+			want:        singleton.PublishResult{Delivered: 1},
+			wantValues:  []interface{}{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := singleton.NewChannelOperator()
+			defer op.CloseAll()
+			op.Register("slow", 1)
+			op.Subscribe("news", "slow", tt.policy)
+
+			if got := op.Publish("news", 1, tt.timeout); got != (singleton.PublishResult{Delivered: 1}) {
+				t.Fatalf("Publish() first = %+v, want it delivered", got)
+			}
+
+			if tt.receiveLate {
+				go func() {
+					time.Sleep(10 * time.Millisecond)
+					op.Receive("slow", time.Second)
+				}()
+			}
+			if got := op.Publish("news", 2, tt.timeout); got != tt.want {
+				t.Errorf("Publish() second = %+v, want %+v", got, tt.want)
+			}
+
+			for _, want := range tt.wantValues {
+				if got, err := op.Receive("slow", time.Second); err != nil || got != want {
+					t.Errorf("Receive() = %v, %v, want %v", got, err, want)
+				}
+			}
+			if stats, _ := op.Stats("slow"); stats.Dropped != tt.wantDropped || stats.Len != 0 {
+				t.Errorf("Stats() = %v, want %d dropped and an empty buffer", stats, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestPublishBroadcast(t *testing.T) {
+	op := singleton.NewChannelOperator()
+	defer op.CloseAll()
+	for _, name := range []string{"a", "b", "c"} {
+		op.Register(name, 2)
+		op.Subscribe("news", name, singleton.DropNewest)
+	}
+
+	if got := op.Publish("nobody", "x", 0); got != (singleton.PublishResult{}) {
+		t.Errorf("Publish() to a topic without subscribers = %+v", got)
+	}
+	if got := op.Publish("news", "x", 0); got.Delivered != 3 {
+		t.Errorf("Publish() = %+v, want it delivered to every subscriber", got)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if got, err := op.Receive(name, 0); err != nil || got != "x" {
+			t.Errorf("Receive(%s) = %v, %v, want x", name, got, err)
+		}
+	}
+}
+
+func TestFanIn(t *testing.T) {
+	op := singleton.NewChannelOperator()
+	defer op.CloseAll()
+	op.Register("merged", 0)
+	op.Register("a", 4)
+	op.Register("b", 4)
+
+	if _, err := op.FanIn("merged", "a", "missing"); !errors.Is(err, singleton.ErrNoChannel) {
+		t.Errorf("FanIn() unknown source error = %v, want ErrNoChannel", err)
+	}
+	if _, err := op.FanIn("merged", "merged"); err == nil {
+		t.Errorf("FanIn() into itself error = nil")
+	}
+
+	stop, err := op.FanIn("merged", "a", "b")
+	if err != nil {
+		t.Fatalf("FanIn() error = %v", err)
+	}
+	op.Send("a", 1, 0)
+	op.Send("b", 2, 0)
+	op.Send("a", 3, 0)
+
+	var got []int
+	for i := 0; i < 3; i++ {
+		value, err := op.Receive("merged", time.Second)
+		if err != nil {
+			t.Fatalf("Receive() error = %v", err)
+		}
+		got = append(got, value.(int))
+	}
+	sort.Ints(got)
+	if len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Errorf("merged values = %v, want [1 2 3]", got)
+	}
+
+	stop()
+	stop() // stop can be called more than once
+	op.Send("a", 4, 0)
+	if _, err := op.Receive("merged", 10*time.Millisecond); !errors.Is(err, singleton.ErrTimeout) {
+		t.Errorf("Receive() after stop error = %v, want ErrTimeout", err)
+	}
+}
+
+func TestFanInEndsOnUnregister(t *testing.T) {
+	op := singleton.NewChannelOperator()
+	op.Register("merged", 0)
+	op.Register("a", 1)
+
+	stop, _ := op.FanIn("merged", "a")
+	op.Send("a", 1, 0) // the forwarder blocks on the unbuffered target
+
+	stopped := make(chan struct{})
+	go func() {
+		op.Unregister("merged")
+		stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("stop() did not return after the target was unregistered")
+	}
+	op.CloseAll()
+}
+
+// TestPubSubConcurrentUse publishes, subscribes and fans in from many goroutines, run it with -race
+func TestPubSubConcurrentUse(t *testing.T) {
+	op := singleton.NewChannelOperator()
+	defer op.CloseAll()
+	op.Register("merged", 16)
+	op.Register("a", 4)
+	op.Register("b", 4)
+	op.Subscribe("news", "a", singleton.DropOldest)
+	op.Subscribe("news", "b", singleton.Block)
+
+	stop, err := op.FanIn("merged", "a", "b")
+	if err != nil {
+		t.Fatalf("FanIn() error = %v", err)
+	}
+
+	const publishers, perPublisher = 4, 50
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		delivered int
+	)
+	for p := 0; p < publishers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perPublisher; i++ {
+				result := op.Publish("news", p*perPublisher+i, time.Second)
+				mu.Lock()
+				delivered += result.Delivered
+				mu.Unlock()
+			}
+		}(p)
+	}
+
+	// churn a subscriber while the topic is busy
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			op.Register("temp", 1)
+			op.Subscribe("news", "temp", singleton.DropNewest)
+			op.Snapshot()
+			op.Unregister("temp")
+		}
+	}()
+
+	quit := make(chan struct{})
+	received := make(chan int)
+	go func() {
+		count := 0
+		for {
+			select {
+			case <-quit:
+				// the forwarding has stopped so whatever is left is in the buffer
+				for {
+					if _, err := op.Receive("merged", 0); err != nil {
+						received <- count
+						return
+					}
+					count++
+				}
+			default:
+			}
+			if _, err := op.Receive("merged", 10*time.Millisecond); err == nil {
+				count++
+			}
+		}
+	}()
+
+	wg.Wait()
+	stop()
+	close(quit)
+	count := <-received
+
+	a, _ := op.Stats("a")
+	b, _ := op.Stats("b")
+	merged, _ := op.Stats("merged")
+	// every value forwarded from a and b was either received from the merged channel or
+	// dropped when the forwarding stopped
+	if int64(count) != merged.Sent || a.Received+b.Received != merged.Sent+merged.Dropped {
+		t.Errorf("received %d, merged %v, forwarded %d", count, merged, a.Received+b.Received)
+	}
+	if count > delivered {
+		t.Errorf("received %d merged values but only %d were delivered", count, delivered)
+	}
+}
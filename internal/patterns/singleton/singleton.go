@@ -11,33 +11,39 @@ import (
 // at a time. It is used to provide a global point of access to
 // the object.
 
-// NOTE: The full functionality for the Channel Operator will not be implemented for simplicity sake.
-// the idea is to show how a singleton can be created using the sync package's sync.Once.
+// NOTE: The idea is to show how a singleton can be created using the sync package's sync.Once.
+// The once and the instance live in a Holder, see holder.go, and the ChannelOperator itself
+// is implemented in channels.go.
 
-var (
-	instance *ChannelOperator
-	once     sync.Once
-)
+// operator holds the ChannelOperator singleton returned by New
+var operator = NewHolder(newChannelOperator)
 
 // ChannelOperator is the struct that is a singleton
 // it is used as a single point of monitoring channels
 type ChannelOperator struct {
-	ID       string
-	Channels map[string]chan interface{}
+	ID string
+
+	mu       sync.RWMutex
+	channels map[string]*channel
+	topics   map[string][]subscription // topics are the subscribers of every topic, see pubsub.go
 }
 
 // New will return a new ChannelOperator struct using the go once.Do
 // function to ensure that only one instance of the ChannelOperator
 // struct is created.
 func New() *ChannelOperator {
-	// use the sync package's sync.Once to ensure that only one instance of the ChannelOperator
-	// on repeated calls to the New function the ChannelOperator will not be created again
-	// instead the existing ChannelOperator will be returned
-	once.Do(func() {
-		instance = &ChannelOperator{
-			Channels: make(map[string]chan interface{}),
-			ID:       uuid.NewString(), // assign a unique ID to easily show that only one instance is created
-		}
-	})
-	return instance
+	// the holder uses the sync package's sync.Once to ensure that only one instance of the
+	// ChannelOperator is created, on repeated calls to the New function the ChannelOperator
+	// will not be created again instead the existing ChannelOperator will be returned
+	return operator.Get()
+}
+
+// newChannelOperator will return a new ChannelOperator struct, it is only called through
+// the holder of New outside of the tests
+func newChannelOperator() *ChannelOperator {
+	return &ChannelOperator{
+		channels: make(map[string]*channel),
+		topics:   make(map[string][]subscription),
+		ID:       uuid.NewString(), // assign a unique ID to easily show that only one instance is created
+	}
 }